@@ -0,0 +1,22 @@
+package archive
+
+import (
+	"cli/cmd/archive/export"
+	"cli/cmd/archive/importer"
+
+	"github.com/spf13/cobra"
+)
+
+func GetCommand() *cobra.Command {
+	archiveCmd := &cobra.Command{
+		Use:   "archive",
+		Short: "Top level command for exporting and importing chain archives through the polygon-edge backup grpc",
+	}
+
+	archiveCmd.AddCommand(
+		export.GetCommand(),
+		importer.GetCommand(),
+	)
+
+	return archiveCmd
+}
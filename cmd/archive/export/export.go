@@ -0,0 +1,144 @@
+package export
+
+import (
+	"fmt"
+	"time"
+
+	"cli/cmd/chain"
+	"cli/cmd/helper"
+
+	"github.com/briandowns/spinner"
+	"github.com/spf13/cobra"
+)
+
+type exportParams struct {
+	From uint64
+	To   uint64
+	Node int
+	Out  string
+}
+
+var (
+	params = &exportParams{}
+)
+
+const (
+	From = "from"
+	To   = "to"
+	Node = "node"
+	Out  = "out"
+)
+
+func GetCommand() *cobra.Command {
+	exportCmd := &cobra.Command{
+		Use:     "export <stake-id>",
+		Short:   "Streams blocks of the chain of a stack into a local archive file",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: preRunCommand,
+		Run:     runCommand,
+	}
+
+	setFlags(exportCmd)
+
+	return exportCmd
+}
+
+func setFlags(cmd *cobra.Command) {
+	cmd.Flags().Uint64Var(
+		&params.From,
+		From,
+		0,
+		"the first block of the archive",
+	)
+
+	cmd.Flags().Uint64Var(
+		&params.To,
+		To,
+		0,
+		"the last block of the archive (default the latest block)",
+	)
+
+	cmd.Flags().IntVar(
+		&params.Node,
+		Node,
+		0,
+		"export from this validator (default the first validator)",
+	)
+
+	cmd.Flags().StringVar(
+		&params.Out,
+		Out,
+		"",
+		"the archive file (default <stake-id>-<from>-<to>.dat)",
+	)
+}
+
+func preRunCommand(cmd *cobra.Command, args []string) error {
+	if params.To != 0 && params.To < params.From {
+		return fmt.Errorf("the archive can not end at block %d before block %d", params.To, params.From)
+	}
+
+	return chain.ValidateStack(cmd.Context(), args[0])
+}
+
+func runCommand(cmd *cobra.Command, args []string) {
+	outputter := helper.InitializeOutputter(cmd)
+	defer outputter.WriteOutput()
+
+	namespace := args[0]
+
+	indexes, err := chain.ValidatorIndexes(cmd.Context(), namespace)
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	node := indexes[0]
+	if params.Node != 0 {
+		found := false
+		for _, i := range indexes {
+			found = found || i == params.Node
+		}
+
+		if !found {
+			outputter.SetError(fmt.Errorf("validator %d is not part of stack %s", params.Node, namespace))
+			return
+		}
+
+		node = params.Node
+	}
+
+	out := params.Out
+	if out == "" {
+		to := "latest"
+		if params.To != 0 {
+			to = fmt.Sprint(params.To)
+		}
+
+		out = fmt.Sprintf("%s-%d-%s.dat", namespace, params.From, to)
+	}
+
+	s := spinner.New(spinner.CharSets[14], 110*time.Millisecond, spinner.WithColor("cyan"))
+
+	fmt.Println("\n ")
+	s.Suffix = " Running..."
+	s.Start()
+	defer s.Stop()
+
+	archive, err := chain.ExportArchive(cmd.Context(), namespace, node, params.From, params.To, out)
+	if err != nil {
+		helper.EmitCmd(s, "Chain export is failed", false)
+		outputter.SetError(err)
+		return
+	} else {
+		helper.EmitCmd(s, fmt.Sprintf("Validator %d exported %d blocks 📦", node, archive.Blocks), true)
+	}
+
+	outputter.SetCommandResult(&helper.ArchiveResult{
+		Message: fmt.Sprintf("\nBlocks %d to %d of stack %s are written to %s \n", archive.First, archive.Last, namespace, out),
+		File:    out,
+		First:   archive.First,
+		Last:    archive.Last,
+		Blocks:  archive.Blocks,
+	})
+}
@@ -0,0 +1,107 @@
+package importer
+
+import (
+	"fmt"
+	"time"
+
+	"cli/cmd/chain"
+	"cli/cmd/helper"
+
+	"github.com/briandowns/spinner"
+	"github.com/spf13/cobra"
+)
+
+var (
+	archive *chain.ArchiveRange
+)
+
+func GetCommand() *cobra.Command {
+	importCmd := &cobra.Command{
+		Use:   "import <stake-id> <file>",
+		Short: "Seeds the validators of a stack with the blocks of a chain archive",
+		Long: `Seeds the validators of a stack with the blocks of a chain archive.
+
+The archive is copied onto the data volume of every validator and set as the restore_file
+of their node config, the validators are restarted together and import its blocks before
+they seal again.
+
+The archive has to continue the chain of the stack: every validator has to be behind the
+last block of the archive, and the archive block following its height has to point at the
+block the validator holds. Every stack gets its own genesis, so an archive only seeds the
+stack it was exported from, e.g. to catch up after a restore from an older backup. An
+archive of another chain is refused.
+
+The import of a long chain is bound by --timeout like any other step, raise it for large
+archives.`,
+		Args:    cobra.ExactArgs(2),
+		PreRunE: preRunCommand,
+		Run:     runCommand,
+	}
+
+	return importCmd
+}
+
+func preRunCommand(cmd *cobra.Command, args []string) error {
+	if err := chain.ValidateStack(cmd.Context(), args[0]); err != nil {
+		return err
+	}
+
+	var err error
+	archive, err = chain.ReadArchiveRange(args[1])
+
+	return err
+}
+
+func runCommand(cmd *cobra.Command, args []string) {
+	outputter := helper.InitializeOutputter(cmd)
+	defer outputter.WriteOutput()
+
+	namespace := args[0]
+	file := args[1]
+
+	indexes, err := chain.ValidatorIndexes(cmd.Context(), namespace)
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	if err := chain.CheckArchiveImport(cmd.Context(), namespace, file, archive); err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	s := spinner.New(spinner.CharSets[14], 110*time.Millisecond, spinner.WithColor("cyan"))
+
+	fmt.Println("\n ")
+	s.Suffix = " Running..."
+	s.Start()
+	defer s.Stop()
+
+	for _, i := range indexes {
+		result, err := chain.UploadArchive(cmd.Context(), namespace, i, file)
+		if err != nil {
+			helper.EmitCmd(s, fmt.Sprintf("Validator %d archive copy is failed", i), false)
+			outputter.SetError(err)
+			return
+		} else {
+			helper.EmitCmd(s, result, true)
+		}
+	}
+
+	result, err := chain.ImportArchive(cmd.Context(), namespace, indexes, archive)
+	if err != nil {
+		helper.EmitCmd(s, "Chain archive import is failed", false)
+		outputter.SetError(err)
+		return
+	} else {
+		helper.EmitCmd(s, result, true)
+	}
+
+	outputter.SetCommandResult(&helper.ArchiveResult{
+		Message: fmt.Sprintf("\nStack %s is seeded with blocks %d to %d of %s \n", namespace, archive.First, archive.Last, file),
+		File:    file,
+		First:   archive.First,
+		Last:    archive.Last,
+		Blocks:  archive.Blocks,
+	})
+}
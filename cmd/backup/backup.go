@@ -0,0 +1,24 @@
+package backup
+
+import (
+	"cli/cmd/backup/create"
+	"cli/cmd/backup/list"
+	"cli/cmd/backup/restore"
+
+	"github.com/spf13/cobra"
+)
+
+func GetCommand() *cobra.Command {
+	backupCmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Top level command for volume snapshot backups of the validator data of a stack",
+	}
+
+	backupCmd.AddCommand(
+		create.GetCommand(),
+		list.GetCommand(),
+		restore.GetCommand(),
+	)
+
+	return backupCmd
+}
@@ -0,0 +1,115 @@
+package create
+
+import (
+	"fmt"
+	"time"
+
+	"cli/cmd/chain"
+	"cli/cmd/helper"
+
+	"github.com/briandowns/spinner"
+	"github.com/spf13/cobra"
+)
+
+type createParams struct {
+	Name          string
+	SnapshotClass string
+}
+
+var (
+	params = &createParams{}
+)
+
+const (
+	Name          = "name"
+	SnapshotClass = "snapshot-class"
+)
+
+func GetCommand() *cobra.Command {
+	createCmd := &cobra.Command{
+		Use:     "create <stake-id>",
+		Short:   "Takes a volume snapshot of the data volume of every validator of a stack",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: preRunCommand,
+		Run:     runCommand,
+	}
+
+	setFlags(createCmd)
+
+	return createCmd
+}
+
+func setFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&params.Name,
+		Name,
+		"",
+		"the name of the backup (default backup-<utc time>)",
+	)
+
+	cmd.Flags().StringVar(
+		&params.SnapshotClass,
+		SnapshotClass,
+		"",
+		"the volume snapshot class of the snapshots (default the default class of the cluster)",
+	)
+}
+
+func preRunCommand(cmd *cobra.Command, args []string) error {
+	if params.Name == "" {
+		params.Name = chain.NewBackupName()
+	}
+
+	if err := chain.CheckBackupName(params.Name); err != nil {
+		return err
+	}
+
+	return chain.ValidateStack(cmd.Context(), args[0])
+}
+
+func runCommand(cmd *cobra.Command, args []string) {
+	outputter := helper.InitializeOutputter(cmd)
+	defer outputter.WriteOutput()
+
+	namespace := args[0]
+
+	indexes, err := chain.ValidatorIndexes(cmd.Context(), namespace)
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	s := spinner.New(spinner.CharSets[14], 110*time.Millisecond, spinner.WithColor("cyan"))
+
+	fmt.Println("\n ")
+	s.Suffix = " Running..."
+	s.Start()
+	defer s.Stop()
+
+	var snapshots []helper.BackupEntry
+	for _, i := range indexes {
+		snapshot, err := chain.CreateValidatorSnapshot(cmd.Context(), namespace, params.Name, i, params.SnapshotClass)
+		if err != nil {
+			helper.EmitCmd(s, fmt.Sprintf("Validator %d snapshot is failed", i), false)
+			outputter.SetError(err)
+			return
+		} else {
+			helper.EmitCmd(s, fmt.Sprintf("Validator %d snapshot %s is taken at block %s 📸", i, snapshot.Name, snapshot.BlockHeight), true)
+		}
+
+		snapshots = append(snapshots, helper.BackupEntry{
+			Backup:      snapshot.Backup,
+			Index:       snapshot.Index,
+			Snapshot:    snapshot.Name,
+			BlockHeight: snapshot.BlockHeight,
+			Ready:       snapshot.Ready,
+			Size:        snapshot.Size,
+			Created:     snapshot.Created,
+		})
+	}
+
+	outputter.SetCommandResult(&helper.BackupResult{
+		Message:   fmt.Sprintf("\nBackup %s of stack %s is taken, restore it with backup restore %s %s \n", params.Name, namespace, namespace, params.Name),
+		Snapshots: snapshots,
+	})
+}
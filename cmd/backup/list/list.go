@@ -0,0 +1,82 @@
+package list
+
+import (
+	"fmt"
+
+	"cli/cmd/chain"
+	"cli/cmd/helper"
+
+	"github.com/spf13/cobra"
+)
+
+type listParams struct {
+	Name string
+}
+
+var (
+	params = &listParams{}
+)
+
+const (
+	Name = "name"
+)
+
+func GetCommand() *cobra.Command {
+	listCmd := &cobra.Command{
+		Use:     "list <stake-id>",
+		Short:   "Lists the backups of a stack with the block height of every snapshot",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: preRunCommand,
+		Run:     runCommand,
+	}
+
+	setFlags(listCmd)
+
+	return listCmd
+}
+
+func setFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&params.Name,
+		Name,
+		"",
+		"list the snapshots of this backup only",
+	)
+}
+
+func preRunCommand(cmd *cobra.Command, args []string) error {
+	return chain.ValidateStack(cmd.Context(), args[0])
+}
+
+func runCommand(cmd *cobra.Command, args []string) {
+	outputter := helper.InitializeOutputter(cmd)
+	defer outputter.WriteOutput()
+
+	namespace := args[0]
+
+	snapshots, err := chain.ListBackups(cmd.Context(), namespace, params.Name)
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	backups := map[string]bool{}
+	var entries []helper.BackupEntry
+	for _, snapshot := range snapshots {
+		backups[snapshot.Backup] = true
+		entries = append(entries, helper.BackupEntry{
+			Backup:      snapshot.Backup,
+			Index:       snapshot.Index,
+			Snapshot:    snapshot.Name,
+			BlockHeight: snapshot.BlockHeight,
+			Ready:       snapshot.Ready,
+			Size:        snapshot.Size,
+			Created:     snapshot.Created,
+		})
+	}
+
+	outputter.SetCommandResult(&helper.BackupResult{
+		Message:   fmt.Sprintf("\n%d backups of stack %s, %d snapshots \n", len(backups), namespace, len(entries)),
+		Snapshots: entries,
+	})
+}
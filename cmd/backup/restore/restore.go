@@ -0,0 +1,151 @@
+package restore
+
+import (
+	"fmt"
+	"time"
+
+	"cli/cmd/chain"
+	"cli/cmd/helper"
+
+	"github.com/briandowns/spinner"
+	"github.com/spf13/cobra"
+)
+
+type restoreParams struct {
+	Node           int
+	DeletePrevious bool
+}
+
+var (
+	params = &restoreParams{}
+)
+
+const (
+	Node           = "node"
+	DeletePrevious = "delete-previous"
+)
+
+func GetCommand() *cobra.Command {
+	restoreCmd := &cobra.Command{
+		Use:   "restore <stake-id> <backup>",
+		Short: "Restores the validator data volumes of a stack from a backup",
+		Long: `Restores the validator data volumes of a stack from a backup.
+
+Every validator is stopped first and started again on a new volume provisioned from its
+snapshot, so the chain continues from the block height of the backup. With --node only
+that validator is restored, it syncs the blocks after the backup from its peers.
+
+A stack can also be seeded from a chain archive through the restore_file of the node
+config, see archive import.`,
+		Args:    cobra.ExactArgs(2),
+		PreRunE: preRunCommand,
+		Run:     runCommand,
+	}
+
+	setFlags(restoreCmd)
+
+	return restoreCmd
+}
+
+func setFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(
+		&params.Node,
+		Node,
+		0,
+		"restore the volume of this validator only (default every validator)",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.DeletePrevious,
+		DeletePrevious,
+		false,
+		"delete the volumes the validators used before the restore",
+	)
+}
+
+func preRunCommand(cmd *cobra.Command, args []string) error {
+	if err := chain.CheckBackupName(args[1]); err != nil {
+		return err
+	}
+
+	return chain.ValidateStack(cmd.Context(), args[0])
+}
+
+func runCommand(cmd *cobra.Command, args []string) {
+	outputter := helper.InitializeOutputter(cmd)
+	defer outputter.WriteOutput()
+
+	namespace := args[0]
+	backup := args[1]
+
+	indexes, err := chain.ValidatorIndexes(cmd.Context(), namespace)
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	if params.Node != 0 {
+		found := false
+		for _, i := range indexes {
+			found = found || i == params.Node
+		}
+
+		if !found {
+			outputter.SetError(fmt.Errorf("validator %d is not part of stack %s", params.Node, namespace))
+			return
+		}
+
+		indexes = []int{params.Node}
+	}
+
+	// every validator needs a snapshot in the backup before any of them is stopped
+	snapshots, err := chain.ListBackups(cmd.Context(), namespace, backup)
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	for _, i := range indexes {
+		found := false
+		for _, snapshot := range snapshots {
+			found = found || (snapshot.Index == i && snapshot.Ready)
+		}
+
+		if !found {
+			outputter.SetError(fmt.Errorf("backup %s has no ready snapshot of validator %d", backup, i))
+			return
+		}
+	}
+
+	s := spinner.New(spinner.CharSets[14], 110*time.Millisecond, spinner.WithColor("cyan"))
+
+	fmt.Println("\n ")
+	s.Suffix = " Running..."
+	s.Start()
+	defer s.Stop()
+
+	// a validator restarted with an old volume would be overtaken by the validators still running
+	result, err := chain.StopValidators(cmd.Context(), namespace, indexes)
+	if err != nil {
+		helper.EmitCmd(s, "Validators stop is failed", false)
+		outputter.SetError(err)
+		return
+	} else {
+		helper.EmitCmd(s, result, true)
+	}
+
+	for _, i := range indexes {
+		result, err := chain.RestoreValidatorVolume(cmd.Context(), namespace, backup, i, params.DeletePrevious)
+		if err != nil {
+			helper.EmitCmd(s, fmt.Sprintf("Validator %d restore is failed", i), false)
+			outputter.SetError(err)
+			return
+		} else {
+			helper.EmitCmd(s, result, true)
+		}
+	}
+
+	outputter.SetCommandResult(&helper.BackupResult{
+		Message: fmt.Sprintf("\n%d validators of stack %s restored from backup %s \n", len(indexes), namespace, backup),
+	})
+}
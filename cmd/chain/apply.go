@@ -0,0 +1,189 @@
+package chain
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"cli/cmd/config"
+)
+
+// The apply helpers create an object and, when it already exists, patch it in
+// place or adopt it as is. This keeps every genesis step safe to re-run
+
+func applyConfigMap(ctx context.Context, nsArgs string, configMap *apiv1.ConfigMap) error {
+	client := config.CLIENTSET.CoreV1().ConfigMaps(nsArgs)
+
+	_, err := client.Create(ctx, configMap, metav1.CreateOptions{})
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing, err := client.Get(ctx, configMap.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	existing.Data = configMap.Data
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+
+	return err
+}
+
+func applySecret(ctx context.Context, nsArgs string, secret *apiv1.Secret) error {
+	client := config.CLIENTSET.CoreV1().Secrets(nsArgs)
+
+	_, err := client.Create(ctx, secret, metav1.CreateOptions{})
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing, err := client.Get(ctx, secret.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	existing.Data = nil
+	existing.StringData = secret.StringData
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+
+	return err
+}
+
+func applyServiceAccount(ctx context.Context, nsArgs string, serviceAccount *apiv1.ServiceAccount) error {
+	_, err := config.CLIENTSET.CoreV1().ServiceAccounts(nsArgs).Create(ctx, serviceAccount, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		// the service account only carries its name, the existing one is adopted
+		return nil
+	}
+
+	return err
+}
+
+func applyRole(ctx context.Context, nsArgs string, role *rbacv1.Role) error {
+	client := config.CLIENTSET.RbacV1().Roles(nsArgs)
+
+	_, err := client.Create(ctx, role, metav1.CreateOptions{})
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing, err := client.Get(ctx, role.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	existing.Rules = role.Rules
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+
+	return err
+}
+
+func applyRoleBinding(ctx context.Context, nsArgs string, roleBinding *rbacv1.RoleBinding) error {
+	_, err := config.CLIENTSET.RbacV1().RoleBindings(nsArgs).Create(ctx, roleBinding, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		// the role of a binding is immutable, the existing binding is adopted
+		return nil
+	}
+
+	return err
+}
+
+func applyService(ctx context.Context, nsArgs string, service *apiv1.Service) error {
+	client := config.CLIENTSET.CoreV1().Services(nsArgs)
+
+	_, err := client.Create(ctx, service, metav1.CreateOptions{})
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing, err := client.Get(ctx, service.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	// the cluster IP is immutable, a service turning headless or back is replaced
+	if (existing.Spec.ClusterIP == apiv1.ClusterIPNone) != (service.Spec.ClusterIP == apiv1.ClusterIPNone) {
+		if err := client.Delete(ctx, service.Name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+
+		_, err = client.Create(ctx, service, metav1.CreateOptions{})
+
+		return err
+	}
+
+	// everything else is taken from the desired spec
+	existing.Spec.Type = service.Spec.Type
+	existing.Spec.Selector = service.Spec.Selector
+	existing.Spec.Ports = service.Spec.Ports
+	existing.Spec.PublishNotReadyAddresses = service.Spec.PublishNotReadyAddresses
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+
+	return err
+}
+
+func applyPVC(ctx context.Context, nsArgs string, pvc *apiv1.PersistentVolumeClaim) error {
+	_, err := config.CLIENTSET.CoreV1().PersistentVolumeClaims(nsArgs).Create(ctx, pvc, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		// claims are mostly immutable and hold chain data, so they are adopted
+		return nil
+	}
+
+	return err
+}
+
+func applyStatefulSet(ctx context.Context, nsArgs string, sts *appsv1.StatefulSet) error {
+	client := config.CLIENTSET.AppsV1().StatefulSets(nsArgs)
+
+	_, err := client.Create(ctx, sts, metav1.CreateOptions{})
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing, err := client.Get(ctx, sts.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	// the selector and service name are immutable
+	existing.Spec.Replicas = sts.Spec.Replicas
+	existing.Spec.Template = sts.Spec.Template
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+
+	return err
+}
+
+func applyCronJob(ctx context.Context, nsArgs string, cronJob *batchv1.CronJob) error {
+	client := config.CLIENTSET.BatchV1().CronJobs(nsArgs)
+
+	_, err := client.Create(ctx, cronJob, metav1.CreateOptions{})
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	existing, err := client.Get(ctx, cronJob.Name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	existing.Spec = cronJob.Spec
+	_, err = client.Update(ctx, existing, metav1.UpdateOptions{})
+
+	return err
+}
+
+func applyJob(ctx context.Context, nsArgs string, job *batchv1.Job) error {
+	_, err := config.CLIENTSET.BatchV1().Jobs(nsArgs).Create(ctx, job, metav1.CreateOptions{})
+	if errors.IsAlreadyExists(err) {
+		// a job template is immutable, the running or finished job is adopted
+		return nil
+	}
+
+	return err
+}
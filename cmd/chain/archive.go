@@ -0,0 +1,591 @@
+package chain
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/encoding/protowire"
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
+
+	"cli/cmd/config"
+)
+
+const (
+	// archiveGRPCPort serves the system service of polygon-edge, its Export call streams the chain
+	archiveGRPCPort     = 9632
+	archiveExportMethod = "/v1.System/Export"
+
+	// archiveRestorePath is where the archive is copied to on the data volume of a validator
+	archiveRestorePath = "/data/archive.dat"
+)
+
+type blockResponse struct {
+	Result *struct {
+		Hash common.Hash `json:"hash"`
+	} `json:"result"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// ArchiveRange is the block range held by a chain archive
+type ArchiveRange struct {
+	First  uint64
+	Last   uint64
+	Blocks int
+}
+
+// ExportArchive streams blocks from to to of the chain from validator i into the file at path,
+// through a port forward to the grpc port of its pod. A to of zero exports up to the latest block
+func ExportArchive(ctx context.Context, nsArgs string, i int, from uint64, to uint64, path string) (*ArchiveRange, error) {
+	ctx, cancel := stepContext(ctx)
+	defer cancel()
+
+	address, stop, err := portForwardValidator(nsArgs, i, archiveGRPCPort)
+	if err != nil {
+		return nil, err
+	}
+	defer stop()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = exportBlocks(ctx, address, from, to, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		_ = os.Remove(path)
+
+		return nil, fmt.Errorf("validator %d can not export the chain: %w", i, err)
+	}
+
+	return ReadArchiveRange(path)
+}
+
+// ReadArchiveRange reads the block range of the chain archive at path. An archive is the rlp
+// encoded metadata of the exported chain followed by its rlp encoded blocks
+func ReadArchiveRange(path string) (*ArchiveRange, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stream := rlp.NewStream(bufio.NewReader(file), 0)
+	if _, err := stream.Raw(); err != nil {
+		return nil, fmt.Errorf("%s is not a chain archive: %w", path, err)
+	}
+
+	archive := &ArchiveRange{}
+	for {
+		block, err := stream.Raw()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s is not a chain archive: %w", path, err)
+		}
+
+		number, err := archiveBlockNumber(block)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a chain archive: %w", path, err)
+		}
+
+		if archive.Blocks == 0 {
+			archive.First = number
+		}
+
+		archive.Last = number
+		archive.Blocks++
+	}
+
+	if archive.Blocks == 0 {
+		return nil, fmt.Errorf("chain archive %s holds no blocks", path)
+	}
+
+	return archive, nil
+}
+
+// CheckArchiveImport makes sure the archive at path continues the chain of the stack. Every
+// validator has to be behind the last block of the archive, and the archive block following its
+// height has to point at the block it holds at that height, which an archive of another genesis never does
+func CheckArchiveImport(ctx context.Context, nsArgs string, path string, archive *ArchiveRange) error {
+	indexes, err := ValidatorIndexes(ctx, nsArgs)
+	if err != nil {
+		return err
+	}
+
+	heights := map[int]uint64{}
+	following := map[uint64]bool{}
+	for _, i := range indexes {
+		height, err := blockNumber(ctx, nsArgs, i)
+		if err != nil {
+			return fmt.Errorf("the block height of validator %d is unknown: %w", i, err)
+		}
+
+		if height >= archive.Last {
+			return fmt.Errorf("validator %d is at block %d, the archive ends at block %d and has nothing to import", i, height, archive.Last)
+		}
+
+		if height+1 < archive.First {
+			return fmt.Errorf("validator %d is at block %d, the archive starts at block %d and misses the blocks in between", i, height, archive.First)
+		}
+
+		heights[i] = height
+		following[height+1] = true
+	}
+
+	parents, err := archiveParentHashes(path, following)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range indexes {
+		hash, err := blockHash(ctx, nsArgs, i, heights[i])
+		if err != nil {
+			return fmt.Errorf("block %d of validator %d is unknown: %w", heights[i], i, err)
+		}
+
+		if parents[heights[i]+1] != hash {
+			return fmt.Errorf("block %d of the archive does not follow block %d of validator %d, the archive belongs to another chain",
+				heights[i]+1, heights[i], i)
+		}
+	}
+
+	return nil
+}
+
+// archiveParentHashes returns the parent hashes of the given blocks of the chain archive at path
+func archiveParentHashes(path string, numbers map[uint64]bool) (map[uint64]common.Hash, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	stream := rlp.NewStream(bufio.NewReader(file), 0)
+	if _, err := stream.Raw(); err != nil {
+		return nil, fmt.Errorf("%s is not a chain archive: %w", path, err)
+	}
+
+	parents := map[uint64]common.Hash{}
+	for len(parents) < len(numbers) {
+		block, err := stream.Raw()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s is not a chain archive: %w", path, err)
+		}
+
+		number, err := archiveBlockNumber(block)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a chain archive: %w", path, err)
+		}
+
+		if !numbers[number] {
+			continue
+		}
+
+		parent, err := archiveParentHash(block)
+		if err != nil {
+			return nil, fmt.Errorf("%s is not a chain archive: %w", path, err)
+		}
+
+		parents[number] = parent
+	}
+
+	return parents, nil
+}
+
+// blockHash returns the hash of block number of the chain of validator i
+func blockHash(ctx context.Context, nsArgs string, i int, number uint64) (common.Hash, error) {
+	request := []byte(fmt.Sprintf(`{"jsonrpc":"2.0","method":"eth_getBlockByNumber","params":["%s",false],"id":1}`, hexutil.EncodeUint64(number)))
+
+	data, err := config.CLIENTSET.CoreV1().RESTClient().Post().
+		Namespace(nsArgs).
+		Resource("pods").
+		Name(fmt.Sprintf("validator-node-%v-0:8545", i)).
+		SubResource("proxy").
+		SetHeader("Content-Type", "application/json").
+		Body(request).
+		DoRaw(ctx)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	var response blockResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return common.Hash{}, err
+	}
+
+	if response.Error != nil {
+		return common.Hash{}, fmt.Errorf("eth_getBlockByNumber: %s", response.Error.Message)
+	}
+
+	if response.Result == nil {
+		return common.Hash{}, fmt.Errorf("eth_getBlockByNumber: block %d not found", number)
+	}
+
+	return response.Result.Hash, nil
+}
+
+// UploadArchive copies the chain archive at path onto the data volume of validator i
+func UploadArchive(ctx context.Context, nsArgs string, i int, path string) (string, error) {
+	ctx, cancel := stepContext(ctx)
+	defer cancel()
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	if err := execValidator(ctx, nsArgs, i, fmt.Sprintf("cat > %s", archiveRestorePath), file); err != nil {
+		return "", fmt.Errorf("the archive can not be copied to validator %d: %w", i, err)
+	}
+
+	return fmt.Sprintf("Chain archive is successfully copied to validator %d 📦", i), nil
+}
+
+// ImportArchive restarts the validators with the uploaded archive as the restore file of their
+// node config and waits until each of them has imported the last block of the archive. The
+// restore file is dropped from the node config and the volume afterwards
+func ImportArchive(ctx context.Context, nsArgs string, indexes []int, archive *ArchiveRange) (string, error) {
+	ctx, cancel := stepContext(ctx)
+	defer cancel()
+
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+	requestBody.RestoreFile = archiveRestorePath
+
+	for _, i := range indexes {
+		if err := applyConfigMap(ctx, nsArgs, newNodeConfigMap(nsArgs, i, requestBody)); err != nil {
+			return "", err
+		}
+	}
+
+	// every validator restarts at once, a validator left running would keep sealing blocks the archive does not have
+	if err := replaceValidatorPods(ctx, nsArgs, indexes); err != nil {
+		return "", err
+	}
+
+	for _, i := range indexes {
+		if err := waitForArchiveImport(ctx, nsArgs, i, archive.Last); err != nil {
+			return "", err
+		}
+	}
+
+	requestBody.RestoreFile = ""
+	for _, i := range indexes {
+		if err := applyConfigMap(ctx, nsArgs, newNodeConfigMap(nsArgs, i, requestBody)); err != nil {
+			return "", err
+		}
+
+		if err := execValidator(ctx, nsArgs, i, fmt.Sprintf("rm -f %s", archiveRestorePath), nil); err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("%d validators successfully imported blocks %d to %d 📦", len(indexes), archive.First, archive.Last), nil
+}
+
+// waitForArchiveImport waits until validator i reports the block last. polygon-edge imports the
+// restore file before it starts sealing, a broken archive makes it exit
+func waitForArchiveImport(ctx context.Context, nsArgs string, i int, last uint64) error {
+	err := pollFor(ctx, 3*time.Second, func() (bool, error) {
+		pod, err := config.CLIENTSET.CoreV1().Pods(nsArgs).Get(ctx, fmt.Sprintf("validator-node-%v-0", i), metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.RestartCount > 0 {
+				return false, fmt.Errorf("validator %d exited while importing the archive, see the logs of pod %s", i, pod.Name)
+			}
+		}
+
+		height, err := blockNumber(ctx, nsArgs, i)
+
+		return err == nil && height >= last, nil
+	})
+	if err != nil {
+		return fmt.Errorf("validator %d did not import block %d: %w", i, last, err)
+	}
+
+	return nil
+}
+
+// exportBlocks calls the Export stream of the polygon-edge grpc server at address and writes the
+// data of every event to out. The call is made with a plain http2 client, grpc frames every
+// message with a compression flag and a big endian length
+func exportBlocks(ctx context.Context, address string, from uint64, to uint64, out io.Writer) error {
+	var message []byte
+	message = protowire.AppendTag(message, 1, protowire.VarintType)
+	message = protowire.AppendVarint(message, from)
+	message = protowire.AppendTag(message, 2, protowire.VarintType)
+	message = protowire.AppendVarint(message, to)
+
+	body := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(body[1:], uint32(len(message)))
+	body = append(body, message...)
+
+	client := &http.Client{
+		Transport: &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, cfg *tls.Config) (net.Conn, error) {
+				var dialer net.Dialer
+				return dialer.DialContext(ctx, network, addr)
+			},
+		},
+	}
+
+	request, err := http.NewRequestWithContext(ctx, "POST", "http://"+address+archiveExportMethod, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/grpc")
+	request.Header.Set("TE", "trailers")
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("grpc export answered %s", response.Status)
+	}
+
+	// a call failing before its first message answers with the status in the headers
+	if err := grpcStatus(response.Header); err != nil {
+		return err
+	}
+
+	header := make([]byte, 5)
+	for {
+		if _, err := io.ReadFull(response.Body, header); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		if header[0] != 0 {
+			return errors.New("grpc export answered a compressed message")
+		}
+
+		event := make([]byte, binary.BigEndian.Uint32(header[1:]))
+		if _, err := io.ReadFull(response.Body, event); err != nil {
+			return err
+		}
+
+		data, err := exportEventData(event)
+		if err != nil {
+			return err
+		}
+
+		if _, err := out.Write(data); err != nil {
+			return err
+		}
+	}
+
+	return grpcStatus(response.Trailer)
+}
+
+// exportEventData returns the archive data of an ExportEvent, its field 4
+func exportEventData(event []byte) ([]byte, error) {
+	var data []byte
+
+	for len(event) > 0 {
+		number, kind, n := protowire.ConsumeTag(event)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+
+		event = event[n:]
+
+		if number == 4 && kind == protowire.BytesType {
+			value, n := protowire.ConsumeBytes(event)
+			if n < 0 {
+				return nil, protowire.ParseError(n)
+			}
+
+			data = append(data, value...)
+			event = event[n:]
+
+			continue
+		}
+
+		n = protowire.ConsumeFieldValue(number, kind, event)
+		if n < 0 {
+			return nil, protowire.ParseError(n)
+		}
+
+		event = event[n:]
+	}
+
+	return data, nil
+}
+
+func grpcStatus(header http.Header) error {
+	status := header.Get("Grpc-Status")
+	if status == "" || status == "0" {
+		return nil
+	}
+
+	message, _ := url.PathUnescape(header.Get("Grpc-Message"))
+
+	return fmt.Errorf("grpc export failed with status %s: %s", status, message)
+}
+
+// archiveParentHash reads the parent hash of an rlp encoded block, the first field of its header
+func archiveParentHash(block []byte) (common.Hash, error) {
+	content, _, err := rlp.SplitList(block)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	header, _, err := rlp.SplitList(content)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	parent, _, err := rlp.SplitString(header)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	if len(parent) != common.HashLength {
+		return common.Hash{}, fmt.Errorf("parent hash of %d bytes", len(parent))
+	}
+
+	return common.BytesToHash(parent), nil
+}
+
+// archiveBlockNumber reads the number of an rlp encoded block, the ninth field of its header
+func archiveBlockNumber(block []byte) (uint64, error) {
+	content, _, err := rlp.SplitList(block)
+	if err != nil {
+		return 0, err
+	}
+
+	header, _, err := rlp.SplitList(content)
+	if err != nil {
+		return 0, err
+	}
+
+	for field := 0; field < 8; field++ {
+		if _, _, header, err = rlp.Split(header); err != nil {
+			return 0, err
+		}
+	}
+
+	number, _, err := rlp.SplitUint64(header)
+
+	return number, err
+}
+
+// portForwardValidator forwards a free local port to port of the pod of validator i and returns
+// the local address together with the function stopping the forward
+func portForwardValidator(nsArgs string, i int, port int) (string, func(), error) {
+	transport, upgrader, err := spdy.RoundTripperFor(config.RESTCONFIG)
+	if err != nil {
+		return "", nil, err
+	}
+
+	podURL := config.CLIENTSET.CoreV1().RESTClient().Post().
+		Namespace(nsArgs).
+		Resource("pods").
+		Name(fmt.Sprintf("validator-node-%v-0", i)).
+		SubResource("portforward").
+		URL()
+
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", podURL)
+
+	stopChan := make(chan struct{})
+	readyChan := make(chan struct{})
+
+	forwarder, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, []string{fmt.Sprintf("0:%d", port)}, stopChan, readyChan, io.Discard, io.Discard)
+	if err != nil {
+		return "", nil, err
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- forwarder.ForwardPorts()
+	}()
+
+	select {
+	case <-readyChan:
+	case err := <-errChan:
+		return "", nil, fmt.Errorf("port %d of validator %d can not be forwarded: %w", port, i, err)
+	}
+
+	ports, err := forwarder.GetPorts()
+	if err != nil {
+		close(stopChan)
+
+		return "", nil, err
+	}
+
+	return fmt.Sprintf("127.0.0.1:%d", ports[0].Local), func() { close(stopChan) }, nil
+}
+
+// execValidator runs script in the node container of validator i with stdin as its input
+func execValidator(ctx context.Context, nsArgs string, i int, script string, stdin io.Reader) error {
+	request := config.CLIENTSET.CoreV1().RESTClient().Post().
+		Namespace(nsArgs).
+		Resource("pods").
+		Name(fmt.Sprintf("validator-node-%v-0", i)).
+		SubResource("exec").
+		VersionedParams(&apiv1.PodExecOptions{
+			Container: fmt.Sprintf("validator-node-%v", i),
+			Command:   []string{"sh", "-c", script},
+			Stdin:     stdin != nil,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(config.RESTCONFIG, "POST", request.URL())
+	if err != nil {
+		return err
+	}
+
+	var stdout, stderr bytes.Buffer
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: &stdout,
+		Stderr: &stderr,
+	})
+	if err != nil && stderr.Len() > 0 {
+		return fmt.Errorf("%w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+
+	return err
+}
@@ -0,0 +1,446 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/dynamicinformer"
+	"k8s.io/client-go/tools/cache"
+
+	"cli/cmd/config"
+)
+
+const (
+	// snapshotGroup serves the csi volume snapshots, the external snapshotter has to be installed
+	snapshotGroup   = "snapshot.storage.k8s.io"
+	snapshotVersion = "v1"
+
+	// backupLabel groups the snapshots of one backup, blockHeightLabel records the height of each
+	backupLabel      = "polygon-supernet-cli/backup"
+	blockHeightLabel = "polygon-supernet-cli/block-height"
+)
+
+// snapshotResource serves the volume snapshots, the group is served by a crd so the typed
+// clientset has no client for it
+var snapshotResource = schema.GroupVersionResource{Group: snapshotGroup, Version: snapshotVersion, Resource: "volumesnapshots"}
+
+// volumeSnapshot is the part of a snapshot.storage.k8s.io/v1 VolumeSnapshot the cli reads and writes
+type volumeSnapshot struct {
+	APIVersion string                `json:"apiVersion"`
+	Kind       string                `json:"kind"`
+	Metadata   metav1.ObjectMeta     `json:"metadata"`
+	Spec       volumeSnapshotSpec    `json:"spec"`
+	Status     *volumeSnapshotStatus `json:"status,omitempty"`
+}
+
+type volumeSnapshotSpec struct {
+	VolumeSnapshotClassName *string              `json:"volumeSnapshotClassName,omitempty"`
+	Source                  volumeSnapshotSource `json:"source"`
+}
+
+type volumeSnapshotSource struct {
+	PersistentVolumeClaimName *string `json:"persistentVolumeClaimName,omitempty"`
+}
+
+type volumeSnapshotStatus struct {
+	ReadyToUse   *bool              `json:"readyToUse,omitempty"`
+	RestoreSize  *resource.Quantity `json:"restoreSize,omitempty"`
+	CreationTime *metav1.Time       `json:"creationTime,omitempty"`
+	Error        *struct {
+		Message *string `json:"message,omitempty"`
+	} `json:"error,omitempty"`
+}
+
+// BackupSnapshot is the snapshot of the data volume of one validator
+type BackupSnapshot struct {
+	Backup      string
+	Index       int
+	Name        string
+	BlockHeight string
+	Ready       bool
+	Size        string
+	Created     string
+}
+
+// NewBackupName returns the name of a backup taken now
+func NewBackupName() string {
+	return fmt.Sprintf("backup-%s", time.Now().UTC().Format("20060102-150405"))
+}
+
+// CheckBackupName makes sure the snapshot and claim names derived from the backup name are valid
+func CheckBackupName(backup string) error {
+	if errs := validation.IsDNS1123Label(backup); len(errs) > 0 {
+		return fmt.Errorf("invalid backup name %q: %s", backup, errs[0])
+	}
+
+	if errs := validation.IsValidLabelValue(backup); len(errs) > 0 {
+		return fmt.Errorf("invalid backup name %q: %s", backup, errs[0])
+	}
+
+	return nil
+}
+
+// CreateValidatorSnapshot takes a snapshot of the data volume of validator i, labelled with the
+// block height of the validator, and waits until it is ready to use
+func CreateValidatorSnapshot(ctx context.Context, nsArgs string, backup string, i int, snapshotClass string) (*BackupSnapshot, error) {
+	ctx, cancel := stepContext(ctx)
+	defer cancel()
+
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	// the snapshot is taken from the running node, the height is the one it reported just before
+	height, err := blockNumber(ctx, nsArgs, i)
+	if err != nil {
+		return nil, fmt.Errorf("the block height of validator %d is unknown: %w", i, err)
+	}
+
+	claim := requestBody.validatorClaim(i)
+	snapshot := volumeSnapshot{
+		APIVersion: snapshotGroup + "/" + snapshotVersion,
+		Kind:       "VolumeSnapshot",
+		Metadata: metav1.ObjectMeta{
+			Name:      snapshotName(backup, i),
+			Namespace: nsArgs,
+			Labels: map[string]string{
+				managedByLabel:     managedByValue,
+				backupLabel:        backup,
+				blockHeightLabel:   strconv.FormatUint(height, 10),
+				validatorNodeLabel: fmt.Sprintf("validator-node%v", i),
+			},
+		},
+		Spec: volumeSnapshotSpec{
+			Source: volumeSnapshotSource{PersistentVolumeClaimName: &claim},
+		},
+	}
+
+	if snapshotClass != "" {
+		snapshot.Spec.VolumeSnapshotClassName = &snapshotClass
+	}
+
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&snapshot)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := snapshotClient(nsArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := client.Create(ctx, &unstructured.Unstructured{Object: object}, metav1.CreateOptions{}); err != nil {
+		return nil, snapshotError(err)
+	}
+
+	return waitForSnapshot(ctx, nsArgs, snapshotName(backup, i))
+}
+
+// ListBackups returns the snapshots of the stack, optionally of one backup, oldest first
+func ListBackups(ctx context.Context, nsArgs string, backup string) ([]BackupSnapshot, error) {
+	selector := map[string]string{managedByLabel: managedByValue}
+	if backup != "" {
+		selector[backupLabel] = backup
+	}
+
+	client, err := snapshotClient(nsArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := client.List(ctx, metav1.ListOptions{LabelSelector: labels.SelectorFromSet(selector).String()})
+	if err != nil {
+		return nil, snapshotError(err)
+	}
+
+	snapshots := []BackupSnapshot{}
+	for _, item := range list.Items {
+		snapshot, err := toBackupSnapshot(&item)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, snapshot)
+	}
+
+	sort.Slice(snapshots, func(a, b int) bool {
+		if snapshots[a].Backup != snapshots[b].Backup {
+			return snapshots[a].Backup < snapshots[b].Backup
+		}
+
+		return snapshots[a].Index < snapshots[b].Index
+	})
+
+	return snapshots, nil
+}
+
+// StopValidators scales the statefulsets of the validators to zero and waits until their pods are
+// gone, so no validator serves blocks newer than the backup being restored
+func StopValidators(ctx context.Context, nsArgs string, indexes []int) (string, error) {
+	ctx, cancel := stepContext(ctx)
+	defer cancel()
+
+	var replicas int32 = 0
+
+	for _, i := range indexes {
+		if err := scaleValidator(ctx, nsArgs, i, replicas); err != nil {
+			return "", err
+		}
+	}
+
+	informer := newInformer(config.CLIENTSET.CoreV1().RESTClient(), "pods", nsArgs, &apiv1.Pod{}, 0, byLabels(map[string]string{
+		"app":       "polygon-edge-network",
+		"namespace": nsArgs,
+	}))
+
+	err := waitFor(ctx, func() (bool, error) {
+		for _, i := range indexes {
+			_, exists, err := storedObject(informer, nsArgs, fmt.Sprintf("validator-node-%v-0", i))
+			if err != nil || exists {
+				return false, err
+			}
+		}
+
+		return true, nil
+	}, informer)
+	if err != nil {
+		return "", fmt.Errorf("validators did not stop: %w", err)
+	}
+
+	return fmt.Sprintf("%d validators are successfully stopped ⏸️", len(indexes)), nil
+}
+
+// RestoreValidatorVolume provisions a new data volume for validator i from its snapshot in backup,
+// points the statefulset of the validator at it and waits until the validator runs again. The
+// volume used before is kept unless deletePrevious is set
+func RestoreValidatorVolume(ctx context.Context, nsArgs string, backup string, i int, deletePrevious bool) (string, error) {
+	ctx, cancel := stepContext(ctx)
+	defer cancel()
+
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+
+	snapshot, err := getSnapshot(ctx, nsArgs, snapshotName(backup, i))
+	if err != nil {
+		return "", err
+	}
+
+	if !snapshot.Ready {
+		return "", fmt.Errorf("snapshot %s is not ready to use", snapshot.Name)
+	}
+
+	size := resource.MustParse(requestBody.Storage.Size)
+	if restoreSize, err := resource.ParseQuantity(snapshot.Size); err == nil && restoreSize.Cmp(size) > 0 {
+		size = restoreSize
+	}
+
+	previous := requestBody.validatorClaim(i)
+	claim := fmt.Sprintf("polygon-edge-validator-%v-%s-pvc", i, backup)
+
+	pvc := newValidatorPVC(nsArgs, i, requestBody)
+	pvc.Name = claim
+	pvc.Spec.Resources.Requests[apiv1.ResourceStorage] = size
+	pvc.Spec.DataSource = &apiv1.TypedLocalObjectReference{
+		APIGroup: toGetStringPtr(snapshotGroup),
+		Kind:     "VolumeSnapshot",
+		Name:     snapshot.Name,
+	}
+
+	if err := applyPVC(ctx, nsArgs, pvc); err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("validator-node-%v", i)
+	statefulSet, err := config.CLIENTSET.AppsV1().StatefulSets(nsArgs).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	var replicas int32 = 1
+	statefulSet.Spec.Replicas = &replicas
+	for _, volume := range statefulSet.Spec.Template.Spec.Volumes {
+		if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == previous {
+			volume.PersistentVolumeClaim.ClaimName = claim
+		}
+	}
+
+	if _, err := config.CLIENTSET.AppsV1().StatefulSets(nsArgs).Update(ctx, statefulSet, metav1.UpdateOptions{}); err != nil {
+		return "", err
+	}
+
+	// the claim is only recorded once the validator uses it, resize and destroy act on the recorded one
+	if err := storeValidatorClaim(ctx, nsArgs, i, claim, previous, deletePrevious); err != nil {
+		return "", err
+	}
+
+	if err := waitForStatefulSetRollout(ctx, nsArgs, name); err != nil {
+		return "", fmt.Errorf("validator %d did not start from snapshot %s: %w", i, snapshot.Name, err)
+	}
+
+	if deletePrevious && previous != claim {
+		err := config.CLIENTSET.CoreV1().PersistentVolumeClaims(nsArgs).Delete(ctx, previous, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+
+		return fmt.Sprintf("Validator %d is successfully restored to block %s, %s is removed 💾", i, snapshot.BlockHeight, previous), nil
+	}
+
+	return fmt.Sprintf("Validator %d is successfully restored to block %s, %s is kept 💾", i, snapshot.BlockHeight, previous), nil
+}
+
+func snapshotName(backup string, i int) string {
+	return fmt.Sprintf("%s-node%v", backup, i)
+}
+
+// toBackupSnapshot converts a snapshot read through the dynamic client, a snapshot the driver
+// failed to take is an error
+func toBackupSnapshot(object *unstructured.Unstructured) (BackupSnapshot, error) {
+	var item volumeSnapshot
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object.Object, &item); err != nil {
+		return BackupSnapshot{}, err
+	}
+
+	snapshot := BackupSnapshot{
+		Backup:      item.Metadata.Labels[backupLabel],
+		Name:        item.Metadata.Name,
+		BlockHeight: item.Metadata.Labels[blockHeightLabel],
+	}
+
+	fmt.Sscanf(item.Metadata.Labels[validatorNodeLabel], "validator-node%d", &snapshot.Index)
+
+	if status := item.Status; status != nil {
+		if status.Error != nil && status.Error.Message != nil {
+			return snapshot, fmt.Errorf("snapshot %s failed: %s", item.Metadata.Name, *status.Error.Message)
+		}
+
+		snapshot.Ready = status.ReadyToUse != nil && *status.ReadyToUse
+
+		if status.RestoreSize != nil {
+			snapshot.Size = status.RestoreSize.String()
+		}
+
+		if status.CreationTime != nil {
+			snapshot.Created = status.CreationTime.UTC().Format(time.RFC3339)
+		}
+	}
+
+	return snapshot, nil
+}
+
+func getSnapshot(ctx context.Context, nsArgs string, name string) (*BackupSnapshot, error) {
+	client, err := snapshotClient(nsArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := client.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot, err := toBackupSnapshot(object)
+	if err != nil {
+		return nil, err
+	}
+
+	return &snapshot, nil
+}
+
+func waitForSnapshot(ctx context.Context, nsArgs string, name string) (*BackupSnapshot, error) {
+	client, err := dynamic.NewForConfig(config.RESTCONFIG)
+	if err != nil {
+		return nil, err
+	}
+
+	informer := dynamicinformer.NewFilteredDynamicInformer(client, snapshotResource, nsArgs, 0, cache.Indexers{}, byName(name)).Informer()
+
+	var snapshot BackupSnapshot
+	err = waitFor(ctx, func() (bool, error) {
+		object, exists, err := storedObject(informer, nsArgs, name)
+		if err != nil || !exists {
+			return false, err
+		}
+
+		snapshot, err = toBackupSnapshot(object.(*unstructured.Unstructured))
+
+		return snapshot.Ready, err
+	}, informer)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot %s is not ready to use: %w", name, err)
+	}
+
+	return &snapshot, nil
+}
+
+// snapshotClient returns the client of the volume snapshots of the namespace
+func snapshotClient(nsArgs string) (dynamic.ResourceInterface, error) {
+	client, err := dynamic.NewForConfig(config.RESTCONFIG)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Resource(snapshotResource).Namespace(nsArgs), nil
+}
+
+// snapshotError explains a missing volume snapshot api
+func snapshotError(err error) error {
+	if errors.IsNotFound(err) {
+		return fmt.Errorf("the cluster serves no %s volume snapshots, install the csi external snapshotter: %w", snapshotGroup, err)
+	}
+
+	return err
+}
+
+func scaleValidator(ctx context.Context, nsArgs string, i int, replicas int32) error {
+	scale, err := config.CLIENTSET.AppsV1().StatefulSets(nsArgs).GetScale(ctx, fmt.Sprintf("validator-node-%v", i), metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	scale.Spec.Replicas = replicas
+	_, err = config.CLIENTSET.AppsV1().StatefulSets(nsArgs).UpdateScale(ctx, scale.Name, scale, metav1.UpdateOptions{})
+
+	return err
+}
+
+// storeValidatorClaim records claim as the data volume claim of restored validator i in the
+// stack state. A previous claim of a restore which is kept is recorded so destroy still finds it
+func storeValidatorClaim(ctx context.Context, nsArgs string, i int, claim string, previous string, deletePrevious bool) error {
+	return updateStackState(ctx, nsArgs, func(state *stackState) {
+		if state.Claims == nil {
+			state.Claims = map[int]string{}
+		}
+
+		state.Claims[i] = claim
+
+		// the volume genesis created is found by its name, only the claims of restores are recorded
+		if deletePrevious || previous == claim || previous == fmt.Sprintf("polygon-edge-validator-%v-pvc", i) {
+			return
+		}
+
+		for _, kept := range state.KeptClaims {
+			if kept == previous {
+				return
+			}
+		}
+
+		state.KeptClaims = append(state.KeptClaims, previous)
+	})
+}
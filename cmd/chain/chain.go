@@ -1,14 +1,53 @@
 package chain
 
-import (
-	// "fmt"
-	// "time"
+import "time"
 
+const (
+	// nodeImage is the polygon-edge image run by the helper job and the validators
+	nodeImage = "0xpolygon/polygon-edge:0.9.0"
+
+	// fetchImage runs the init container fetching the validator secrets when none is given
+	fetchImage = "alpine:3.18"
+
+	// storageSize is the requested size of every validator data volume
+	storageSize = "10Gi"
+
+	// storageClass is the storage class created for and used by the validator data volumes
+	storageClass = "polygonsc"
+
+	// serviceType is the type of the polygon-edge-svc service exposing the validators
+	serviceType = "LoadBalancer"
+
+	// blockConfirmation is the number of rootchain blocks the relayer waits for
+	blockConfirmation = "64"
+
+	// chainId is the chain id used when none is given, and the first one tried by auto assignment
+	chainId = "51001"
+
+	// vaultTokenTTL is the renewal period of a per-stack child token when none is given
+	vaultTokenTTL = "768h"
+
+	// vaultLoginTTL is the lifetime of the tokens pods get from the kubernetes auth method when none is given
+	vaultLoginTTL = "1h"
+
+	// vaultAuthMount is the path the kubernetes auth method is enabled at when none is given
+	vaultAuthMount = "kubernetes"
 )
 
+// vaultMinTokenPeriod is the shortest renewal period of a child token, vaultRenewSchedule
+// renews it every 10 minutes
+const vaultMinTokenPeriod = 20 * time.Minute
+
+const (
+	ConsensusIBFT    = "ibft"
+	ConsensusPolyBFT = "polybft"
+)
 
+const (
+	VaultAuthToken      = "token"
+	VaultAuthKubernetes = "kubernetes"
+)
 
 // func getStakeIdInfo(nsArgs string) (*batchv1.Job, error) {
 // 	return config.CLIENTSET.BatchV1().Jobs(nsArgs).Get(context.TODO(), "polygon-edge-job", metav1.GetOptions{})
 // }
-
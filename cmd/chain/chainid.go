@@ -0,0 +1,78 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"cli/cmd/config"
+)
+
+// GetUsedChainIds returns the chain ids recorded on every managed stack, mapped to their stake id
+func GetUsedChainIds(ctx context.Context) (map[string]string, error) {
+	namespaces, err := config.CLIENTSET.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(map[string]string{
+			managedByLabel: managedByValue,
+		}).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	used := map[string]string{}
+
+	for _, ns := range namespaces.Items {
+		id, ok := ns.Labels[chainIdLabel]
+		if !ok {
+			// stacks created before chain ids were configurable all use the default
+			requestBody, err := loadStackRequest(ctx, ns.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			id = requestBody.ChainID
+		}
+
+		used[id] = ns.Name
+	}
+
+	return used, nil
+}
+
+// CheckChainId fails when another managed stack already uses the chain id
+func CheckChainId(ctx context.Context, id string) error {
+	used, err := GetUsedChainIds(ctx)
+	if err != nil {
+		return err
+	}
+
+	if stakeId, ok := used[id]; ok {
+		return fmt.Errorf("chain id %s is already used by stack %s, pass another --chain-id or use --auto-chain-id", id, stakeId)
+	}
+
+	return nil
+}
+
+// AssignChainId returns the lowest chain id, starting from the default, not used by any managed stack
+func AssignChainId(ctx context.Context) (string, error) {
+	used, err := GetUsedChainIds(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	id, err := strconv.ParseUint(chainId, 10, 64)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		if _, ok := used[strconv.FormatUint(id, 10)]; !ok {
+			return strconv.FormatUint(id, 10), nil
+		}
+
+		id++
+	}
+}
@@ -0,0 +1,223 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"cli/cmd/config"
+)
+
+// ValidateStack makes sure the namespace exists and was created by genesis
+func ValidateStack(ctx context.Context, nsArgs string) error {
+	ns, err := config.CLIENTSET.CoreV1().Namespaces().Get(ctx, nsArgs, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if _, ok := ns.Labels["total-node"]; !ok {
+		return fmt.Errorf("namespace %s is not a polygon-edge stack", nsArgs)
+	}
+
+	return nil
+}
+
+func DeleteLoadBalancer(ctx context.Context, nsArgs string) (string, error) {
+	err := config.CLIENTSET.CoreV1().Services(nsArgs).Delete(ctx, "polygon-edge-svc", metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	return "LoadBalancer is successfully removed 📦", nil
+}
+
+func DeleteStateFulSet(ctx context.Context, nsArgs string) (string, error) {
+	propagation := metav1.DeletePropagationForeground
+
+	err := config.CLIENTSET.AppsV1().StatefulSets(nsArgs).DeleteCollection(
+		ctx,
+		metav1.DeleteOptions{PropagationPolicy: &propagation},
+		metav1.ListOptions{
+			LabelSelector: labels.SelectorFromSet(map[string]string{
+				"app":       "polygon-edge-network",
+				"namespace": nsArgs,
+			}).String(),
+		},
+	)
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	return "Statefulset is successfully removed 🕹️", nil
+}
+
+func DeletePVCAndService(ctx context.Context, nsArgs string, keepPVC bool) (string, error) {
+	// removed validators below the highest index may have kept their volume
+	totalNode, err := highestValidatorIndex(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+
+	for i := 1; i <= totalNode; i++ {
+		node := fmt.Sprintf("validator-node%v-svc", i)
+
+		err := config.CLIENTSET.CoreV1().Services(nsArgs).Delete(ctx, node, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+	}
+
+	if keepPVC {
+		return "Validator Service is successfully removed, PersistentVolumeClaim is kept 💾", nil
+	}
+
+	for i := 1; i <= totalNode; i++ {
+		node := fmt.Sprintf("polygon-edge-validator-%v-pvc", i)
+
+		err := config.CLIENTSET.CoreV1().PersistentVolumeClaims(nsArgs).Delete(ctx, node, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+	}
+
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+
+	// the volumes of validators restored from a backup
+	for _, claim := range requestBody.Claims {
+		err := config.CLIENTSET.CoreV1().PersistentVolumeClaims(nsArgs).Delete(ctx, claim, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+	}
+
+	state, err := loadStackState(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+
+	// the volumes removed validators kept, their indexes may be above the highest one in use
+	for _, claim := range state.KeptClaims {
+		err := config.CLIENTSET.CoreV1().PersistentVolumeClaims(nsArgs).Delete(ctx, claim, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+	}
+
+	// the encrypted secrets file of the file secrets backend
+	err = config.CLIENTSET.CoreV1().PersistentVolumeClaims(nsArgs).Delete(ctx, secretsFilePVC, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	return "PersistentVolumeClaim & Validator Service is successfully removed 💾", nil
+}
+
+func DeleteNodeConfigMap(ctx context.Context, nsArgs string) (string, error) {
+	totalNode, err := highestValidatorIndex(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+
+	for i := 1; i <= totalNode; i++ {
+		var configMapName string = fmt.Sprintf("validator-node%v-config", i)
+
+		err := config.CLIENTSET.CoreV1().ConfigMaps(nsArgs).Delete(ctx, configMapName, metav1.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return "", err
+		}
+	}
+
+	return "Validator node config is successfully removed 📜", nil
+}
+
+// DeleteConfigMap removes the helper job and vault config, and the namespace itself unless keepNamespace is set
+func DeleteConfigMap(ctx context.Context, nsArgs string, keepNamespace bool) (string, error) {
+	ctx, cancel := stepContext(ctx)
+	defer cancel()
+
+	propagation := metav1.DeletePropagationBackground
+
+	err := config.CLIENTSET.BatchV1().Jobs(nsArgs).Delete(ctx, "polygon-edge-job", metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	err = config.CLIENTSET.CoreV1().Secrets(nsArgs).Delete(ctx, vaultSecretName, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	if err := deleteLegacyVaultConfigMap(ctx, nsArgs); err != nil {
+		return "", err
+	}
+
+	if keepNamespace {
+		return "Initialize-Crypto is successfully removed, namespace is kept 🔌", nil
+	}
+
+	if err := deleteNameSpace(ctx, nsArgs); err != nil {
+		return "", err
+	}
+
+	return "Namespace is successfully removed 🔌", nil
+}
+
+// DeleteStorageClass removes the shared polygonsc StorageClass when no other stack still uses it
+func DeleteStorageClass(ctx context.Context, nsArgs string) (string, error) {
+	pvcs, err := config.CLIENTSET.CoreV1().PersistentVolumeClaims("").List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	for _, pvc := range pvcs.Items {
+		if pvc.Namespace == nsArgs || pvc.Spec.StorageClassName == nil {
+			continue
+		}
+
+		if *pvc.Spec.StorageClassName == storageClass {
+			return "StorageClass is still used by other stacks, skipped 🗄️", nil
+		}
+	}
+
+	err = config.CLIENTSET.StorageV1().StorageClasses().Delete(ctx, storageClass, metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	return "StorageClass is successfully removed 🗄️", nil
+}
+
+// deleteLegacyVaultConfigMap removes the config map older stacks kept the vault token in
+func deleteLegacyVaultConfigMap(ctx context.Context, nsArgs string) error {
+	err := config.CLIENTSET.CoreV1().ConfigMaps(nsArgs).Delete(ctx, "vaultconfig-cm", metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func deleteNameSpace(ctx context.Context, nsArgs string) error {
+	err := config.CLIENTSET.CoreV1().Namespaces().Delete(ctx, nsArgs, metav1.DeleteOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil
+		}
+
+		return err
+	}
+
+	informer := newInformer(config.CLIENTSET.CoreV1().RESTClient(), "namespaces", "", &apiv1.Namespace{}, 0, byName(nsArgs))
+	if err := waitForDeletion(ctx, informer, "", nsArgs); err != nil {
+		return fmt.Errorf("namespace %s was not removed: %w", nsArgs, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,381 @@
+package chain
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/yaml"
+
+	"cli/cmd/config"
+)
+
+// The chart templates are produced by rendering the regular manifests with
+// sentinel values and replacing those with helm template expressions
+const (
+	helmNamespaceSentinel    = "helm-release-namespace"
+	helmNodeIndexSentinel    = 424242
+	helmVaultUrlSentinel     = "helm-vault-url"
+	helmImageSentinel        = "helm-node-image"
+	helmServiceSentinel      = "helm-service-type"
+	helmStorageSentinel      = "434343Gi"
+	helmPassphraseSentinel   = "helm-secrets-passphrase"
+	helmStorageClassSentinel = "helm-storage-class"
+)
+
+// helmPorts are the ports of a validator with the sentinels they are rendered with. The builders
+// use the ports as is, setHelmPortSentinels swaps them in the fields known to carry them
+var helmPorts = []struct {
+	port     int32
+	sentinel int32
+	value    string
+}{
+	{9632, 419632, "$.Values.ports.grpc"},
+	{8545, 418545, "$.Values.ports.jsonrpc"},
+	{5001, 415001, "$.Values.ports.prometheus"},
+	{1478, 411478, "$.Values.ports.libp2p"},
+}
+
+type HelmImageValues struct {
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+}
+
+type HelmStorageValues struct {
+	ClassName          string `json:"className"`
+	Size               string `json:"size"`
+	CreateStorageClass bool   `json:"createStorageClass"`
+}
+
+type HelmPortValues struct {
+	GRPC       int `json:"grpc"`
+	JSONRPC    int `json:"jsonrpc"`
+	Prometheus int `json:"prometheus"`
+	Libp2p     int `json:"libp2p"`
+}
+
+// HelmSecretsValues configures the file secrets backend, a random passphrase is generated when it is empty
+type HelmSecretsValues struct {
+	Passphrase string `json:"passphrase"`
+}
+
+type HelmVaultValues struct {
+	Url   string `json:"url"`
+	Token string `json:"token"`
+}
+
+type HelmValues struct {
+	NodeCount   int                `json:"nodeCount"`
+	Relayer     bool               `json:"relayer"`
+	Image       HelmImageValues    `json:"image"`
+	Storage     HelmStorageValues  `json:"storage"`
+	ServiceType string             `json:"serviceType"`
+	Ports       HelmPortValues     `json:"ports"`
+	Vault       HelmVaultValues    `json:"vault"`
+	Secrets     *HelmSecretsValues `json:"secrets,omitempty"`
+}
+
+type helmTemplate struct {
+	file    string
+	objects []runtime.Object
+	// perNode wraps the template in a range over the node indexes up to nodeCount
+	perNode bool
+	guard   string
+}
+
+// ExportHelmChart writes a chart reproducing the stack into dir and returns the written paths
+func ExportHelmChart(requestBody ConfigRequest, dir string) ([]string, error) {
+	totalNode, err := strconv.Atoi(requestBody.NumOfNodes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid total node %q: %w", requestBody.NumOfNodes, err)
+	}
+
+	requestBody = requestBody.WithDefaults()
+
+	repository, tag := requestBody.Image, "latest"
+	if index := strings.LastIndex(requestBody.Image, ":"); index > strings.LastIndex(requestBody.Image, "/") {
+		repository, tag = requestBody.Image[:index], requestBody.Image[index+1:]
+	}
+
+	values := HelmValues{
+		NodeCount: totalNode,
+		Relayer:   requestBody.Consensus == ConsensusPolyBFT && requestBody.BridgeJSONRPC != "",
+		Image:     HelmImageValues{Repository: repository, Tag: tag},
+		Storage: HelmStorageValues{
+			ClassName:          requestBody.Storage.ClassName,
+			Size:               requestBody.Storage.Size,
+			CreateStorageClass: requestBody.Storage.ClassName == storageClass,
+		},
+		ServiceType: requestBody.ServiceType,
+		Ports:       HelmPortValues{GRPC: 9632, JSONRPC: 8545, Prometheus: 5001, Libp2p: 1478},
+		// the token is never exported, it has to be passed at install time
+		Vault: HelmVaultValues{Url: config.VaultUrl},
+	}
+
+	if requestBody.SecretsBackend == SecretsBackendFile {
+		values.Secrets = &HelmSecretsValues{}
+	}
+
+	templates, err := newHelmTemplates(requestBody)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	write := func(name string, data []byte) error {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return err
+		}
+
+		files = append(files, file)
+
+		return os.WriteFile(file, data, 0644)
+	}
+
+	chart := fmt.Sprintf(`apiVersion: v2
+name: %s
+description: polygon-edge validator stack exported by polygon-supernet-cli
+type: application
+version: 0.1.0
+appVersion: %q
+`, toLabelValue(strings.ToLower(requestBody.Name)), values.Image.Tag)
+
+	if err := write("Chart.yaml", []byte(chart)); err != nil {
+		return nil, err
+	}
+
+	valuesData, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := write("values.yaml", valuesData); err != nil {
+		return nil, err
+	}
+
+	for _, template := range templates {
+		data, err := template.render()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := write(filepath.Join("templates", template.file), data); err != nil {
+			return nil, err
+		}
+	}
+
+	return files, nil
+}
+
+func newHelmTemplates(requestBody ConfigRequest) ([]helmTemplate, error) {
+	nsArgs := helmNamespaceSentinel
+	i := helmNodeIndexSentinel
+
+	requestBody.NumOfNodes = strconv.Itoa(helmNodeIndexSentinel)
+
+	// a helper job running the node image follows image.tag
+	if requestBody.Images.Helper == requestBody.Image {
+		requestBody.Images.Helper = helmImageSentinel
+	}
+
+	requestBody.Image = helmImageSentinel
+	requestBody.Storage.ClassName = helmStorageClassSentinel
+	if requestBody.Storage.Preset == "" {
+		requestBody.Storage.Preset = StoragePresetGCE
+	}
+	requestBody.Storage.Size = helmStorageSentinel
+	requestBody.ServiceType = helmServiceSentinel
+
+	// the builders read the vault url from config, point it at the sentinel while rendering
+	vaultUrl := config.VaultUrl
+	config.VaultUrl = helmVaultUrlSentinel
+
+	defer func() {
+		config.VaultUrl = vaultUrl
+	}()
+
+	// the helper job needs the secrets backend before it runs, and the validators need the genesis
+	// it writes, so both are installed as ordered pre-install hooks
+	secretsObjects, err := getSecretsBackend(requestBody).objects(nsArgs, requestBody)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, object := range secretsObjects {
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			return nil, err
+		}
+
+		annotations := map[string]string{
+			"helm.sh/hook":        "pre-install",
+			"helm.sh/hook-weight": "-10",
+		}
+
+		if secret, ok := object.(*apiv1.Secret); ok {
+			annotations["helm.sh/hook-delete-policy"] = "before-hook-creation"
+
+			if _, ok := secret.StringData["passphrase"]; ok {
+				secret.StringData["passphrase"] = helmPassphraseSentinel
+			}
+		}
+
+		accessor.SetAnnotations(annotations)
+	}
+
+	// the secrets file volume is claimed by a hook, so the class it uses has to be installed before it
+	class := newStorageClass(requestBody)
+	class.Name = helmStorageClassSentinel
+	if requestBody.SecretsBackend == SecretsBackendFile {
+		class.Annotations = map[string]string{
+			"helm.sh/hook":        "pre-install",
+			"helm.sh/hook-weight": "-15",
+		}
+	}
+
+	helperJob := newHelperJob(nsArgs, nsArgs, requestBody)
+	helperJob.Annotations = map[string]string{
+		"helm.sh/hook":               "pre-install",
+		"helm.sh/hook-weight":        "-5",
+		"helm.sh/hook-delete-policy": "before-hook-creation",
+	}
+
+	templates := []helmTemplate{
+		{file: "secrets-backend.yaml", objects: secretsObjects},
+		{file: "helper-job.yaml", objects: []runtime.Object{helperJob}},
+		{file: "node-configmap.yaml", objects: []runtime.Object{newNodeConfigMap(nsArgs, i, requestBody)}, perNode: true},
+		{file: "storageclass.yaml", objects: []runtime.Object{class}, guard: "$.Values.storage.createStorageClass"},
+		{file: "pvc.yaml", objects: []runtime.Object{newValidatorPVC(nsArgs, i, requestBody)}, perNode: true},
+		{file: "service.yaml", objects: []runtime.Object{newValidatorService(nsArgs, i, requestBody)}, perNode: true},
+		{file: "statefulset.yaml", objects: []runtime.Object{newStatefulSet(nsArgs, nsArgs, i, requestBody)}, perNode: true},
+		{file: "loadbalancer.yaml", objects: []runtime.Object{newLoadBalancer(nsArgs, requestBody)}},
+	}
+
+	for _, template := range templates {
+		for _, object := range template.objects {
+			setHelmPortSentinels(object)
+		}
+	}
+
+	return templates, nil
+}
+
+// setHelmPortSentinels swaps the validator ports for their sentinels in the service ports, the
+// container ports, the listen addresses of the node config and the bootnode addresses of the
+// helper job. Other values which happen to equal a port are left alone
+func setHelmPortSentinels(object runtime.Object) {
+	switch object := object.(type) {
+	case *apiv1.Service:
+		for i := range object.Spec.Ports {
+			port := &object.Spec.Ports[i]
+			port.Port = helmPortSentinel(port.Port)
+			if port.TargetPort.Type == intstr.Int {
+				port.TargetPort.IntVal = helmPortSentinel(port.TargetPort.IntVal)
+			}
+		}
+	case *appsv1.StatefulSet:
+		for i := range object.Spec.Template.Spec.Containers {
+			container := &object.Spec.Template.Spec.Containers[i]
+			for j := range container.Ports {
+				container.Ports[j].ContainerPort = helmPortSentinel(container.Ports[j].ContainerPort)
+			}
+		}
+	case *apiv1.ConfigMap:
+		for key, value := range object.Data {
+			for _, p := range helmPorts {
+				value = strings.ReplaceAll(value, fmt.Sprintf(`"0.0.0.0:%d"`, p.port), fmt.Sprintf(`"0.0.0.0:%d"`, p.sentinel))
+			}
+
+			object.Data[key] = value
+		}
+	case *batchv1.Job:
+		for i := range object.Spec.Template.Spec.Containers {
+			container := &object.Spec.Template.Spec.Containers[i]
+			for j, arg := range container.Args {
+				for _, p := range helmPorts {
+					arg = strings.ReplaceAll(arg, fmt.Sprintf(".svc.cluster.local/tcp/%d/", p.port), fmt.Sprintf(".svc.cluster.local/tcp/%d/", p.sentinel))
+				}
+
+				container.Args[j] = arg
+			}
+		}
+	}
+}
+
+func helmPortSentinel(port int32) int32 {
+	for _, p := range helmPorts {
+		if p.port == port {
+			return p.sentinel
+		}
+	}
+
+	return port
+}
+
+func (t helmTemplate) render() ([]byte, error) {
+	manifests, err := MarshalManifests(t.objects)
+	if err != nil {
+		return nil, err
+	}
+
+	manifests = parameterize(manifests)
+
+	var buffer bytes.Buffer
+
+	switch {
+	case t.perNode:
+		buffer.WriteString("{{- range $i := untilStep 1 (int (add1 $.Values.nodeCount)) 1 }}\n")
+		buffer.WriteString(manifests)
+		buffer.WriteString("{{- end }}\n")
+	case t.guard != "":
+		buffer.WriteString(fmt.Sprintf("{{- if %s }}\n", t.guard))
+		buffer.WriteString(manifests)
+		buffer.WriteString("{{- end }}\n")
+	default:
+		buffer.WriteString(manifests)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+var helmReplacements = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(regexp.QuoteMeta(helmNamespaceSentinel)), "{{ $.Release.Namespace }}"},
+	{regexp.MustCompile(`value: ` + helmVaultUrlSentinel), "value: {{ $.Values.vault.url | quote }}"},
+	{regexp.MustCompile(`token: ` + vaultTokenPlaceholder), "token: {{ $.Values.vault.token | quote }}"},
+	{regexp.MustCompile(regexp.QuoteMeta(helmVaultUrlSentinel)), "{{ $.Values.vault.url }}"},
+	{regexp.MustCompile(regexp.QuoteMeta(vaultTokenPlaceholder)), "{{ $.Values.vault.token }}"},
+	{regexp.MustCompile(`value: "` + strconv.Itoa(helmNodeIndexSentinel) + `"`), "value: {{ $.Values.nodeCount | quote }}"},
+	{regexp.MustCompile(strconv.Itoa(helmNodeIndexSentinel)), "{{ $i }}"},
+	{regexp.MustCompile(`relayer\\": false`), `relayer\": {{ and $.Values.relayer (eq $i 1) }}`},
+	{regexp.MustCompile(helmImageSentinel), "{{ $.Values.image.repository }}:{{ $.Values.image.tag }}"},
+	{regexp.MustCompile(helmServiceSentinel), "{{ $.Values.serviceType }}"},
+	{regexp.MustCompile(helmStorageSentinel), "{{ $.Values.storage.size }}"},
+	{regexp.MustCompile(helmPassphraseSentinel), "{{ $.Values.secrets.passphrase | default (randAlphaNum 32) | quote }}"},
+	{regexp.MustCompile(helmStorageClassSentinel), "{{ $.Values.storage.className }}"},
+}
+
+func parameterize(manifests string) string {
+	for _, r := range helmReplacements {
+		manifests = r.pattern.ReplaceAllLiteralString(manifests, r.replacement)
+	}
+
+	for _, p := range helmPorts {
+		manifests = strings.ReplaceAll(manifests, strconv.Itoa(int(p.sentinel)), fmt.Sprintf("{{ %s }}", p.value))
+	}
+
+	return manifests
+}
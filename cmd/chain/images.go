@@ -0,0 +1,104 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"cli/cmd/config"
+)
+
+// ImageConfig selects the images of the helper job and the fetch init containers, and where every
+// image of the stack is pulled from
+type ImageConfig struct {
+	// Helper runs the helper job, the node image by default
+	Helper string `json:"helper,omitempty"`
+
+	// Fetch runs the init container placing the genesis and the secrets on the validator volume
+	Fetch string `json:"fetch,omitempty"`
+
+	// Registry prefixes every image, for clusters pulling from a private mirror
+	Registry string `json:"registry,omitempty"`
+
+	// PullSecrets are [namespace/]name references to docker registry secrets, copied into the stack namespace
+	PullSecrets []string `json:"pullSecrets,omitempty"`
+
+	// AirGapped never installs missing tools at runtime, the helper and fetch images have to ship them
+	AirGapped bool `json:"airGapped,omitempty"`
+}
+
+// image returns the reference of image in the registry of the stack
+func (c ImageConfig) image(image string) string {
+	if c.Registry == "" {
+		return image
+	}
+
+	return strings.TrimSuffix(c.Registry, "/") + "/" + image
+}
+
+func (c ImageConfig) pullSecrets() []apiv1.LocalObjectReference {
+	var secrets []apiv1.LocalObjectReference
+	for _, reference := range c.PullSecrets {
+		_, name := splitPullSecret(reference)
+		secrets = append(secrets, apiv1.LocalObjectReference{Name: name})
+	}
+
+	return secrets
+}
+
+// copyPullSecrets copies the pull secrets of the stack into its namespace, which is created by genesis
+func copyPullSecrets(ctx context.Context, nsArgs string, requestBody ConfigRequest) error {
+	for _, reference := range requestBody.Images.PullSecrets {
+		namespace, name := splitPullSecret(reference)
+
+		source, err := config.CLIENTSET.CoreV1().Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return fmt.Errorf("image pull secret %s: %w", reference, err)
+		}
+
+		secret := &apiv1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: nsArgs,
+			},
+			Type: source.Type,
+			Data: source.Data,
+		}
+
+		_, err = config.CLIENTSET.CoreV1().Secrets(nsArgs).Create(ctx, secret, metav1.CreateOptions{})
+		if err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// splitPullSecret returns the namespace and the name of a pull secret reference, default when no namespace is given
+func splitPullSecret(reference string) (string, string) {
+	if namespace, name, ok := strings.Cut(reference, "/"); ok {
+		return namespace, name
+	}
+
+	return metav1.NamespaceDefault, reference
+}
+
+// installScript makes sure the tools are on the path. Missing ones are installed with apk, or
+// fail the pod in air-gapped mode as there is no registry to install them from
+func (c ImageConfig) installScript(tools []string) string {
+	var script strings.Builder
+
+	for _, tool := range tools {
+		if c.AirGapped {
+			fmt.Fprintf(&script, "command -v %[1]s > /dev/null || { echo \"%[1]s is missing from the image, it can not be installed in air-gapped mode\"; exit 1; }\n", tool)
+		} else {
+			fmt.Fprintf(&script, "command -v %[1]s > /dev/null || apk add --no-cache %[1]s\n", tool)
+		}
+	}
+
+	return script.String()
+}
@@ -2,11 +2,13 @@ package chain
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"time"
+	"strings"
 
 	batchv1 "k8s.io/api/batch/v1"
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"cli/cmd/config"
@@ -20,54 +22,231 @@ type PremineAllo struct {
 }
 
 type ConfigRequest struct {
-	Name              string        `json:"name"`
-	NumOfNodes        string        `json:"totalNode"`
-	GasLimit          string        `json:"gasLimit"`
-	EpochSize         string        `json:"epochSize"`
-	NodePremineAmount string        `json:"nodePremineFund"`
-	Premine           []PremineAllo `json:"premine"`
+	Name              string         `json:"name"`
+	NumOfNodes        string         `json:"totalNode"`
+	GasLimit          string         `json:"gasLimit"`
+	EpochSize         string         `json:"epochSize"`
+	NodePremineAmount string         `json:"nodePremineFund"`
+	Premine           []PremineAllo  `json:"premine"`
+	Image             string         `json:"image,omitempty"`
+	Storage           StorageConfig  `json:"storage,omitempty"`
+	Resources         ResourceConfig `json:"resources,omitempty"`
+	ServiceType       string         `json:"serviceType,omitempty"`
+	HeadlessServices  bool           `json:"headlessServices,omitempty"`
+	Consensus         string         `json:"consensus,omitempty"`
+	ValidatorStake    string         `json:"validatorStake,omitempty"`
+	BridgeJSONRPC     string         `json:"bridgeJsonRpc,omitempty"`
+	BlockConfirmation string         `json:"numBlockConfirmations,omitempty"`
+	ChainID           string         `json:"chainId,omitempty"`
+	SecretsBackend    string         `json:"secretsBackend,omitempty"`
+	Vault             VaultConfig    `json:"vault,omitempty"`
+	Images            ImageConfig    `json:"images,omitempty"`
+	LocalGenesis      bool           `json:"localGenesis,omitempty"`
+	// Claims are the data volume claims of validators restored from a backup, by node index. They
+	// are read from the stack state, never from the request
+	Claims map[int]string `json:"-"`
+	// RestoreFile is the chain archive the validators import on start, it is only set while an archive is imported
+	RestoreFile string `json:"-"`
 }
 
-func CreateConfigMap(requestBody ConfigRequest) (string, string, error) {
+const (
+	// stackConfigAnnotation stores the genesis request on the namespace so a stack can be resumed
+	stackConfigAnnotation = "polygon-supernet-cli/config"
+
+	// the labels below mark every namespace created by the cli so its stacks can be listed
+	managedByLabel = "app.kubernetes.io/managed-by"
+	managedByValue = "polygon-supernet-cli"
+	chainNameLabel = "chain-name"
+	chainIdLabel   = "chain-id"
+)
+
+func CreateConfigMap(ctx context.Context, requestBody ConfigRequest) (string, string, error) {
+	ctx, cancel := stepContext(ctx)
+	defer cancel()
+
 	var nsArgs string = uuid.New().String()
 
-	var passingArgs string = `command="polygon-edge genesis \`
+	requestBody = requestBody.WithDefaults()
 
-	passingArgs = passingArgs + fmt.Sprintf("\n--block-gas-limit %s %s", requestBody.GasLimit, `\`)
-	passingArgs = passingArgs + fmt.Sprintf("\n--epoch-size %s %s", requestBody.EpochSize, `\`)
-	passingArgs = passingArgs + fmt.Sprintf("\n--name %s %s", requestBody.Name, `\`)
-	passingArgs = passingArgs + fmt.Sprintf("\n--chain-id %s %s", "51001", `\`)
-	passingArgs = passingArgs + fmt.Sprintf("\n--consensus %s %s", "ibft", `\`)
+	// the namespace is returned alongside any error so the caller can roll it back
+	err := createNameSpace(ctx, nsArgs, requestBody)
 
-	for _, value := range requestBody.Premine {
-		passingArgs = passingArgs + fmt.Sprintf("\n--premine %s:%s %s", value.Account, value.Amount, `\`)
+	if err != nil {
+		return nsArgs, "", err
 	}
 
-	passingArgs = passingArgs[0:len(passingArgs)-1] + fmt.Sprintf("%s", `"`)
-
-	err := createNameSpace(nsArgs, requestBody.NumOfNodes)
+	err = copyPullSecrets(ctx, nsArgs, requestBody)
 
 	if err != nil {
-		return "", "", err
+		return nsArgs, "", err
 	}
 
-	err = createConfigMap(nsArgs)
+	err = getSecretsBackend(requestBody).setup(ctx, nsArgs, requestBody)
 
 	if err != nil {
-		return "", "", err
+		return nsArgs, "", err
 	}
 
-	err = createHelperJob(nsArgs, nsArgs, requestBody.NumOfNodes, passingArgs, requestBody.NodePremineAmount)
+	if requestBody.LocalGenesis {
+		err = createLocalGenesis(ctx, nsArgs, requestBody)
+	} else {
+		err = createHelperJob(ctx, nsArgs, nsArgs, requestBody)
+	}
 
 	if err != nil {
-		return "", "", err
+		return nsArgs, "", err
 	}
 
 	return nsArgs, "Initialize-Crypto is successfully configured 🔌", nil
 }
 
-func GetStakeId(nsArgs string) (string, error) {
-	result, err := getStakeIdInfo(nsArgs)
+// ResumeConfigMap finishes the Initialize-Crypto step of an existing stack. A running
+// or succeeded helper job is adopted, a failed or missing one is recreated from the
+// request stored on the namespace. A genesis generated by the cli is only generated
+// again when it was never stored
+func ResumeConfigMap(ctx context.Context, nsArgs string) (string, error) {
+	ctx, cancel := stepContext(ctx)
+	defer cancel()
+
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+
+	err = copyPullSecrets(ctx, nsArgs, requestBody)
+
+	if err != nil {
+		return "", err
+	}
+
+	err = getSecretsBackend(requestBody).setup(ctx, nsArgs, requestBody)
+
+	if err != nil {
+		return "", err
+	}
+
+	err = deleteLegacyVaultConfigMap(ctx, nsArgs)
+
+	if err != nil {
+		return "", err
+	}
+
+	if requestBody.LocalGenesis {
+		if hasLocalGenesis(ctx, nsArgs) {
+			return "Initialize-Crypto is already configured 🔌", nil
+		}
+
+		if err := createLocalGenesis(ctx, nsArgs, requestBody); err != nil {
+			return "", err
+		}
+
+		return "Initialize-Crypto is successfully resumed 🔌", nil
+	}
+
+	job, err := config.CLIENTSET.BatchV1().Jobs(nsArgs).Get(ctx, "polygon-edge-job", metav1.GetOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	if err == nil {
+		if job.Status.Succeeded > 0 {
+			return "Initialize-Crypto is already configured 🔌", nil
+		}
+
+		if job.Status.Failed == 0 {
+			if err := waitForHelperJob(ctx, nsArgs, "polygon-edge-job"); err != nil {
+				return "", err
+			}
+
+			return "Initialize-Crypto is successfully resumed 🔌", nil
+		}
+
+		if err := deleteHelperJob(ctx, nsArgs); err != nil {
+			return "", err
+		}
+	}
+
+	storedRequest, err := GetStackRequest(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+
+	err = createHelperJob(ctx, nsArgs, nsArgs, storedRequest.WithDefaults())
+
+	if err != nil {
+		return "", err
+	}
+
+	return "Initialize-Crypto is successfully resumed 🔌", nil
+}
+
+// GetStackRequest returns the genesis request the stack was created with
+func GetStackRequest(ctx context.Context, nsArgs string) (ConfigRequest, error) {
+	var requestBody ConfigRequest
+
+	ns, err := config.CLIENTSET.CoreV1().Namespaces().Get(ctx, nsArgs, metav1.GetOptions{})
+	if err != nil {
+		return requestBody, err
+	}
+
+	data, ok := ns.Annotations[stackConfigAnnotation]
+	if !ok {
+		return requestBody, fmt.Errorf("namespace %s has %w", nsArgs, errNoStackRequest)
+	}
+
+	if err := json.Unmarshal([]byte(data), &requestBody); err != nil {
+		return requestBody, err
+	}
+
+	return requestBody, nil
+}
+
+// storeStackRequest replaces the genesis request stored on the namespace of the stack
+func storeStackRequest(ctx context.Context, nsArgs string, requestBody ConfigRequest) error {
+	ns, err := config.CLIENTSET.CoreV1().Namespaces().Get(ctx, nsArgs, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	request, err := json.Marshal(requestBody)
+	if err != nil {
+		return err
+	}
+
+	if ns.Annotations == nil {
+		ns.Annotations = map[string]string{}
+	}
+
+	ns.Annotations[stackConfigAnnotation] = string(request)
+	_, err = config.CLIENTSET.CoreV1().Namespaces().Update(ctx, ns, metav1.UpdateOptions{})
+
+	return err
+}
+
+func genesisCommand(requestBody ConfigRequest) string {
+	var passingArgs string = `command="polygon-edge genesis \`
+
+	passingArgs = passingArgs + fmt.Sprintf("\n--block-gas-limit %s %s", requestBody.GasLimit, `\`)
+	passingArgs = passingArgs + fmt.Sprintf("\n--epoch-size %s %s", requestBody.EpochSize, `\`)
+	passingArgs = passingArgs + fmt.Sprintf("\n--name %s %s", requestBody.Name, `\`)
+	passingArgs = passingArgs + fmt.Sprintf("\n--chain-id %s %s", requestBody.ChainID, `\`)
+	passingArgs = passingArgs + fmt.Sprintf("\n--consensus %s %s", requestBody.Consensus, `\`)
+
+	if requestBody.Consensus == ConsensusPolyBFT && requestBody.BridgeJSONRPC != "" {
+		passingArgs = passingArgs + fmt.Sprintf("\n--bridge-json-rpc %s %s", requestBody.BridgeJSONRPC, `\`)
+	}
+
+	for _, value := range requestBody.Premine {
+		passingArgs = passingArgs + fmt.Sprintf("\n--premine %s:%s %s", value.Account, value.Amount, `\`)
+	}
+
+	passingArgs = passingArgs[0:len(passingArgs)-1] + fmt.Sprintf("%s", `"`)
+
+	return passingArgs
+}
+
+func GetStakeId(ctx context.Context, nsArgs string) (string, error) {
+	result, err := getStakeIdInfo(ctx, nsArgs)
 	if err != nil {
 		return "", err
 	}
@@ -75,8 +254,8 @@ func GetStakeId(nsArgs string) (string, error) {
 	return result, nil
 }
 
-func GetTotalNodeInNs(nsArgs string) (string, error) {
-	result, err := GetTotalNode(nsArgs)
+func GetTotalNodeInNs(ctx context.Context, nsArgs string) (string, error) {
+	result, err := GetTotalNode(ctx, nsArgs)
 	if err != nil {
 		return "", err
 	}
@@ -84,8 +263,8 @@ func GetTotalNodeInNs(nsArgs string) (string, error) {
 	return result, nil
 }
 
-func GetJobDetails(nsArgs string) (map[string]any, error) {
-	res, err := config.CLIENTSET.BatchV1().Jobs(nsArgs).Get(context.TODO(), "polygon-edge-job", metav1.GetOptions{})
+func GetJobDetails(ctx context.Context, nsArgs string) (map[string]any, error) {
+	res, err := config.CLIENTSET.BatchV1().Jobs(nsArgs).Get(ctx, "polygon-edge-job", metav1.GetOptions{})
 	if err != nil {
 		return map[string]any{}, err
 	}
@@ -100,8 +279,8 @@ func GetJobDetails(nsArgs string) (map[string]any, error) {
 	return store, nil
 }
 
-func GetTotalNode(nsArgs string) (string, error) {
-	job, err := config.CLIENTSET.CoreV1().Namespaces().Get(context.TODO(), nsArgs, metav1.GetOptions{})
+func GetTotalNode(ctx context.Context, nsArgs string) (string, error) {
+	job, err := config.CLIENTSET.CoreV1().Namespaces().Get(ctx, nsArgs, metav1.GetOptions{})
 
 	if err != nil {
 		return "", err
@@ -110,74 +289,86 @@ func GetTotalNode(nsArgs string) (string, error) {
 	return job.Labels["total-node"], nil
 }
 
-func createNameSpace(nsArgs string, totalNode string) error {
-	namespace := &apiv1.Namespace{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "Namespace",
-			APIVersion: "v1",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name: nsArgs,
-			Labels: map[string]string{
-				"total-node": totalNode,
-			},
-		},
+func createNameSpace(ctx context.Context, nsArgs string, requestBody ConfigRequest) error {
+	namespace, err := newNameSpace(nsArgs, requestBody)
+	if err != nil {
+		return err
 	}
 
-	_, err := config.CLIENTSET.CoreV1().Namespaces().Create(context.TODO(), namespace, metav1.CreateOptions{})
+	_, err = config.CLIENTSET.CoreV1().Namespaces().Create(ctx, namespace, metav1.CreateOptions{})
 	if err != nil {
 		return err
 	}
 
-	for {
-		// Get the job
-		job, err := config.CLIENTSET.CoreV1().Namespaces().Get(context.TODO(), nsArgs, metav1.GetOptions{})
-		if err != nil {
-			return err
+	informer := newInformer(config.CLIENTSET.CoreV1().RESTClient(), "namespaces", "", &apiv1.Namespace{}, 0, byName(nsArgs))
+
+	err = waitFor(ctx, func() (bool, error) {
+		object, exists, err := storedObject(informer, "", nsArgs)
+		if err != nil || !exists {
+			return false, err
 		}
 
-		// Check the job status
-		if job.Status.Phase == "Active" {
-			return nil
-		} else if job.Status.Phase == "Terminating" {
-			return fmt.Errorf("job failed")
+		switch object.(*apiv1.Namespace).Status.Phase {
+		case apiv1.NamespaceActive:
+			return true, nil
+		case apiv1.NamespaceTerminating:
+			return false, fmt.Errorf("namespace %s is terminating", nsArgs)
 		}
 
-		time.Sleep(1 * time.Second)
+		return false, nil
+	}, informer)
+	if err != nil {
+		return fmt.Errorf("namespace %s did not become active: %w", nsArgs, err)
 	}
+
+	return nil
 }
 
-func createConfigMap(nsArgs string) error {
-	var configMapName string = "vaultconfig-cm"
-	configMapData := make(map[string]string)
-	configMapData["vaultconfig-node.json"] = fmt.Sprintf(`{
-		"token": "%s",
-		"server_url": "%s",
-		"type": "hashicorp-vault"
-   	}`, config.VaultToken, config.VaultUrl)
+func newNameSpace(nsArgs string, requestBody ConfigRequest) (*apiv1.Namespace, error) {
+	request, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, err
+	}
 
-	// Make ConfigMap
-	configMap := &apiv1.ConfigMap{
+	namespace := &apiv1.Namespace{
 		TypeMeta: metav1.TypeMeta{
-			Kind:       "ConfigMap",
+			Kind:       "Namespace",
 			APIVersion: "v1",
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      configMapName,
-			Namespace: nsArgs,
+			Name: nsArgs,
+			Labels: map[string]string{
+				"total-node":   requestBody.NumOfNodes,
+				managedByLabel: managedByValue,
+				chainNameLabel: toLabelValue(requestBody.Name),
+				chainIdLabel:   requestBody.ChainID,
+			},
+			Annotations: map[string]string{
+				stackConfigAnnotation: string(request),
+			},
 		},
-		Data: configMapData,
 	}
-	_, err := config.CLIENTSET.CoreV1().ConfigMaps(nsArgs).Create(context.TODO(), configMap, metav1.CreateOptions{})
+
+	return namespace, nil
+}
+
+func createHelperJob(ctx context.Context, nsArgs string, stackId string, requestBody ConfigRequest) error {
+	job := newHelperJob(nsArgs, stackId, requestBody)
+
+	err := applyJob(ctx, nsArgs, job)
 	if err != nil {
 		return err
-	} else {
-		return nil
 	}
+
+	return waitForHelperJob(ctx, nsArgs, job.Name)
 }
 
-func createHelperJob(nsArgs string, stackId string, node string, genesis string, nodePremineFund string) error {
+func newHelperJob(nsArgs string, stackId string, requestBody ConfigRequest) *batchv1.Job {
 	var jobName string = "polygon-edge-job"
+	var node string = requestBody.NumOfNodes
+	var nodePremineFund string = requestBody.NodePremineAmount
+	var genesis string = genesisCommand(requestBody)
+
 	envs := []apiv1.EnvVar{
 		{
 			Name:  "NAMESPACE",
@@ -196,69 +387,75 @@ func createHelperJob(nsArgs string, stackId string, node string, genesis string,
 			Value: nodePremineFund,
 		},
 		{
-			Name:  "VAULT_ADDR",
-			Value: config.VaultUrl,
+			Name:  "CONSENSUS",
+			Value: requestBody.Consensus,
 		},
 		{
-			Name:  "VAULT_TOKEN",
-			Value: config.VaultToken,
+			Name:  "VALIDATOR_STAKE",
+			Value: requestBody.ValidatorStake,
 		},
 	}
 
+	backend := getSecretsBackend(requestBody)
+	envs = append(envs, backend.envs(nsArgs, requestBody)...)
+	volumes, volumeMounts := backend.jobVolumes()
+	tools, _ := backend.tools()
+
 	jobSpec := batchv1.JobSpec{
 		Template: apiv1.PodTemplateSpec{
 			Spec: apiv1.PodSpec{
-				RestartPolicy: "OnFailure",
-				Volumes: []apiv1.Volume{
-					{
-						Name: "vault-configcm",
-						VolumeSource: apiv1.VolumeSource{
-							ConfigMap: &apiv1.ConfigMapVolumeSource{
-								LocalObjectReference: apiv1.LocalObjectReference{
-									Name: "vaultconfig-cm",
-								},
-							},
-						},
-					},
-				},
+				RestartPolicy:      "OnFailure",
+				ServiceAccountName: backend.serviceAccount(requestBody),
+				ImagePullSecrets:   requestBody.Images.pullSecrets(),
+				Volumes:            volumes,
 				Containers: []apiv1.Container{
 					{
-						Name:    jobName,
-						Image:   "0xpolygon/polygon-edge:0.9.0",
-						Command: []string{"/bin/sh", "-c"},
-						Env:     envs,
-						VolumeMounts: []apiv1.VolumeMount{
-							{
-								Name:      "vault-configcm",
-								MountPath: "/config",
-							},
-						},
+						Name:         jobName,
+						Image:        requestBody.Images.image(requestBody.Images.Helper),
+						Command:      []string{"/bin/sh", "-c"},
+						Env:          envs,
+						VolumeMounts: volumeMounts,
 						Args: []string{
 							fmt.Sprintf(
 								`         
 							#!/usr/bin/env sh
 
-							# Install jq and curl
-							apk add --no-cache jq
-							apk add curl
+							# Make sure the tools of the script are installed
+							%s
+
+							%s
 				  
 							for i in $(seq 1 $((NUM_OF_NODES)));
 							do 
-							  token=$(jq -r .token /config/vaultconfig-node.json)
-							  server_url=$(jq -r .server_url /config/vaultconfig-node.json)
-							  type=$(jq -r .type /config/vaultconfig-node.json)
-							  name=${STACK_ID}/node${i}
-							  echo "{\"token\": \"$token\", \"server_url\": \"$server_url\", \"type\": \"$type\", \"name\": \"$name\"}" > /home/vaultconfignode${i}.json
-							  polygon-edge secrets init --config /home/vaultconfignode${i}.json --json | jq > /home/node${i}keys.json
+							  init_secrets ${i}
 							done				  
 							
 							%s
 
+							# polybft needs an image whose genesis takes stakes and whose secrets init prints the bls signature
+							if [ "$CONSENSUS" = "polybft" ] && [ -n "$VALIDATOR_STAKE" ] && ! polygon-edge genesis --help 2>&1 | grep -q -- "--stake"; then
+							  echo "the genesis command of this polygon-edge image has no --stake flag, use an image with polybft staking" >&2
+							  exit 1
+							fi
+
 							for i in $(seq 1 $((NUM_OF_NODES))); 
 							do
 							  address=$(jq -r '.[].address' /home/node${i}keys.json)
 							  bls_pubkey=$(jq -r '.[].bls_pubkey' /home/node${i}keys.json)
-							  command=${command}"--ibft-validator ${address}:${bls_pubkey} "
+							  if [ "$CONSENSUS" = "polybft" ]; then
+							    node_id=$(jq -r '.[].node_id' /home/node${i}keys.json)
+							    bls_signature=$(jq -r '.[].bls_signature' /home/node${i}keys.json)
+							    if [ -z "$bls_signature" ] || [ "$bls_signature" = "null" ]; then
+							      echo "secrets init of this polygon-edge image prints no bls_signature, use an image with polybft validator signatures" >&2
+							      exit 1
+							    fi
+							    command=${command}"--validators /dns4/validator-node${i}-svc.${NAMESPACE}.svc.cluster.local/tcp/1478/p2p/${node_id}:${address}:${bls_pubkey}:${bls_signature} "
+							    if [ -n "$VALIDATOR_STAKE" ]; then
+							      command=${command}"--stake ${address}:${VALIDATOR_STAKE} "
+							    fi
+							  else
+							    command=${command}"--ibft-validator ${address}:${bls_pubkey} "
+							  fi
 							done 
 				  
 							for i in $(seq 1 $((NUM_OF_NODES))); 
@@ -276,56 +473,11 @@ func createHelperJob(nsArgs string, stackId string, node string, genesis string,
 							# echo $command
 							eval "$command"
 				  
-							# Set vault variables
+							# Store the genesis and the validator secrets
 							set -e
-				  
-							SECRET_PATH="polygon-edge/data/${STACK_ID}/genesis.json"
-							JSON_FILE_PATH="/genesis.json"
-				  
-							# Read JSON file contents into a variable
-							JSON_CONTENTS="$(cat $JSON_FILE_PATH)"
-				  
-							# Create the secret in Vault
-							
-							curl --header "X-Vault-Token: ${VAULT_TOKEN}" \
-							--request POST \
-							--data "{\"data\": ${JSON_CONTENTS}}" \
-							${VAULT_ADDR}/v1/${SECRET_PATH}
-				  
-							echo "Secret successfully written genesis.json to Vault!"
-				  
-							# Writing public keys to vault in json format
-				  
-							SECRET_PATH="polygon-edge/data/${STACK_ID}"
-				  
-							for i in $(seq 1 $((NUM_OF_NODES))); 
-							do
-							  PUBKEYS_JSON_FILE_PATH="/home/node${i}keys.json"
-							  PUBKEYS_JSON_CONTENTS="$(cat $PUBKEYS_JSON_FILE_PATH)"
-							  curl --header "X-Vault-Token: ${VAULT_TOKEN}" \
-							  --request POST \
-							  --data "{\"data\": ${PUBKEYS_JSON_CONTENTS}}" \
-							  ${VAULT_ADDR}/v1/${SECRET_PATH}/node${i}/keys.json
-				  
-							  echo "Secret successfully written node ${i} keys json to Vault!"
-							done 
-				  
-							# Writing vault secrets config to vault
-				  
-							VAULT_SECRET_PATH="polygon-edge/data/${STACK_ID}"
-				  
-							for i in $(seq 1 $((NUM_OF_NODES))); 
-							do
-							  VAULTCONFIG_JSON_FILE_PATH="/home/vaultconfignode${i}.json"
-							  VAULTCONFIG_JSON_CONTENTS="$(cat $VAULTCONFIG_JSON_FILE_PATH)"
-							  curl --header "X-Vault-Token: ${VAULT_TOKEN}" \
-							  --request POST \
-							  --data "{\"data\": ${VAULTCONFIG_JSON_CONTENTS}}" \
-							  ${VAULT_ADDR}/v1/${VAULT_SECRET_PATH}/node${i}/vaultsecretsconfig.json
-				  
-							  echo "Secret successfully written node ${i} vault secrets config json to Vault!"
-							done 
-				  	`, genesis),
+
+							store_secrets
+				  	`, requestBody.Images.installScript(append([]string{"jq"}, tools...)), backend.jobScript(), genesis),
 						},
 					},
 				},
@@ -345,31 +497,72 @@ func createHelperJob(nsArgs string, stackId string, node string, genesis string,
 		},
 		Spec: jobSpec,
 	}
-	_, err := config.CLIENTSET.BatchV1().Jobs(nsArgs).Create(context.TODO(), job, metav1.CreateOptions{})
-	if err != nil {
-		return err
-	}
 
-	for {
-		// Get the job
-		job, err := config.CLIENTSET.BatchV1().Jobs(nsArgs).Get(context.TODO(), jobName, metav1.GetOptions{})
+	return job
+}
+
+func waitForHelperJob(ctx context.Context, nsArgs string, jobName string) error {
+	informer := newInformer(config.CLIENTSET.BatchV1().RESTClient(), "jobs", nsArgs, &batchv1.Job{}, 0, byName(jobName))
+
+	return waitFor(ctx, func() (bool, error) {
+		object, exists, err := storedObject(informer, nsArgs, jobName)
 		if err != nil {
-			return err
+			return false, err
+		} else if !exists {
+			return false, fmt.Errorf("job %s is gone", jobName)
 		}
 
-		// Check the job status
-		if job.Status.Succeeded == 1 {
-			return nil
+		job := object.(*batchv1.Job)
+		if job.Status.Succeeded > 0 {
+			return true, nil
 		} else if job.Status.Failed > 0 {
-			return fmt.Errorf("job failed")
+			return false, fmt.Errorf("job failed")
 		}
 
-		time.Sleep(1 * time.Second)
+		return false, nil
+	}, informer)
+}
+
+func deleteHelperJob(ctx context.Context, nsArgs string) error {
+	return deleteJob(ctx, nsArgs, "polygon-edge-job")
+}
+
+// deleteJob deletes the job and its pods and waits until it is gone
+func deleteJob(ctx context.Context, nsArgs string, jobName string) error {
+	propagation := metav1.DeletePropagationForeground
+
+	err := config.CLIENTSET.BatchV1().Jobs(nsArgs).Delete(ctx, jobName, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
 	}
+
+	return waitForDeletion(ctx, newInformer(config.CLIENTSET.BatchV1().RESTClient(), "jobs", nsArgs, &batchv1.Job{}, 0, byName(jobName)), nsArgs, jobName)
 }
 
-func getStakeIdInfo(nsArgs string) (string, error) {
-	res, err := config.CLIENTSET.BatchV1().Jobs(nsArgs).Get(context.TODO(), "polygon-edge-job", metav1.GetOptions{})
+// toLabelValue turns an arbitrary string into a valid label value
+func toLabelValue(value string) string {
+	label := []byte(value)
+	for i, c := range label {
+		isAlphaNum := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if !isAlphaNum && c != '-' && c != '_' && c != '.' {
+			label[i] = '-'
+		}
+	}
+
+	if len(label) > 63 {
+		label = label[:63]
+	}
+
+	return strings.Trim(string(label), "-_.")
+}
+
+func getStakeIdInfo(ctx context.Context, nsArgs string) (string, error) {
+	res, err := config.CLIENTSET.BatchV1().Jobs(nsArgs).Get(ctx, "polygon-edge-job", metav1.GetOptions{})
+
+	// a genesis generated by the cli has no helper job, its stack id is the namespace
+	if errors.IsNotFound(err) && hasLocalGenesis(ctx, nsArgs) {
+		return nsArgs, nil
+	}
 
 	if err != nil {
 		return "", err
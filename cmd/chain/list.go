@@ -0,0 +1,67 @@
+package chain
+
+import (
+	"context"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	"cli/cmd/config"
+)
+
+type StackSummary struct {
+	StakeId        string
+	Name           string
+	ChainId        string
+	TotalNode      string
+	CreatedAt      time.Time
+	LoadBalancerIP string
+	Health         string
+}
+
+// ListStacks returns a summary of every stack created by the cli
+func ListStacks(ctx context.Context) ([]StackSummary, error) {
+	namespaces, err := config.CLIENTSET.CoreV1().Namespaces().List(ctx, metav1.ListOptions{
+		LabelSelector: labels.SelectorFromSet(map[string]string{
+			managedByLabel: managedByValue,
+		}).String(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stacks := make([]StackSummary, 0, len(namespaces.Items))
+
+	for _, ns := range namespaces.Items {
+		stack := StackSummary{
+			StakeId:   ns.Name,
+			Name:      ns.Labels[chainNameLabel],
+			TotalNode: ns.Labels["total-node"],
+			CreatedAt: ns.CreationTimestamp.Time,
+		}
+
+		// the stored request keeps the chain name as typed, the label is sanitized. A stack whose
+		// request can not be read is listed without a chain id
+		if requestBody, err := loadStackRequest(ctx, ns.Name); err == nil {
+			if requestBody.Name != "" {
+				stack.Name = requestBody.Name
+			}
+
+			stack.ChainId = requestBody.ChainID
+		}
+
+		if ns.Status.Phase == "Terminating" {
+			stack.Health = "terminating"
+		} else if status, err := GetStackStatus(ctx, ns.Name); err == nil {
+			stack.LoadBalancerIP = status.LoadBalancerIP
+			stack.Health = status.Health
+		} else {
+			stack.Health = HealthDegraded
+		}
+
+		stacks = append(stacks, stack)
+	}
+
+	return stacks, nil
+}
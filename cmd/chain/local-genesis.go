@@ -0,0 +1,340 @@
+package chain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"cli/cmd/config"
+)
+
+const (
+	// localGenesisAnnotation marks a stack whose genesis was generated by the cli and stored in its secrets backend
+	localGenesisAnnotation = "polygon-supernet-cli/local-genesis"
+
+	// istanbulExtraVanity is the zero prefix of the ibft extra data
+	istanbulExtraVanity = 32
+
+	// istanbulDigest is the mix hash of every ibft block
+	istanbulDigest = "0x63746963616c2062797a616e74696e65206661756c7420746f6c6572616e6365"
+
+	// libp2pKeySecp256k1 is the key type of a secp256k1 key in the libp2p protobuf encoding
+	libp2pKeySecp256k1 = 2
+)
+
+// localValidator holds the secrets of one validator in the encoding polygon-edge secrets init writes them in
+type localValidator struct {
+	ValidatorKey string
+	NetworkKey   string
+	Address      common.Address
+	NodeID       string
+}
+
+// localGenesis is a genesis generated by the cli together with the secrets of its validators
+type localGenesis struct {
+	Genesis    []byte
+	Validators []localValidator
+}
+
+// the types below follow the genesis.json of polygon-edge 0.9.0
+type genesisChain struct {
+	Name      string             `json:"name"`
+	Genesis   genesisBlock       `json:"genesis"`
+	Params    genesisChainParams `json:"params"`
+	Bootnodes []string           `json:"bootnodes"`
+}
+
+type genesisBlock struct {
+	Nonce      string                    `json:"nonce"`
+	Timestamp  string                    `json:"timestamp"`
+	ExtraData  string                    `json:"extraData"`
+	GasLimit   string                    `json:"gasLimit"`
+	Difficulty string                    `json:"difficulty"`
+	MixHash    string                    `json:"mixHash"`
+	Coinbase   common.Address            `json:"coinbase"`
+	Alloc      map[string]genesisAccount `json:"alloc"`
+	Number     string                    `json:"number"`
+	GasUsed    string                    `json:"gasUsed"`
+	ParentHash common.Hash               `json:"parentHash"`
+}
+
+type genesisAccount struct {
+	Balance string `json:"balance"`
+}
+
+type genesisChainParams struct {
+	Forks          map[string]genesisFork `json:"forks"`
+	ChainID        int64                  `json:"chainID"`
+	Engine         map[string]any         `json:"engine"`
+	BlockGasTarget uint64                 `json:"blockGasTarget"`
+}
+
+type genesisFork struct {
+	Block uint64 `json:"block"`
+}
+
+// istanbulExtra is the ibft extra data of the genesis block, validators are ecdsa addresses
+type istanbulExtra struct {
+	Validators           []common.Address
+	ProposerSeal         []byte
+	CommittedSeals       [][]byte
+	ParentCommittedSeals [][]byte
+	RoundNumber          []byte
+}
+
+// genesisForks are enabled from the genesis block. London is left out, polygon-edge
+// requires a burn contract for it
+var genesisForks = []string{
+	"homestead", "byzantium", "constantinople", "petersburg", "istanbul",
+	"EIP150", "EIP158", "EIP155", "quorumcalcalignment", "txHashWithType",
+}
+
+// createLocalGenesis generates the validator secrets and the genesis of an ibft stack and
+// stores them in the secrets backend, in place of the helper job
+func createLocalGenesis(ctx context.Context, nsArgs string, requestBody ConfigRequest) error {
+	genesis, err := newLocalGenesis(nsArgs, requestBody, rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	if err := getSecretsBackend(requestBody).storeGenesis(ctx, nsArgs, requestBody, genesis); err != nil {
+		return err
+	}
+
+	ns, err := config.CLIENTSET.CoreV1().Namespaces().Get(ctx, nsArgs, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if ns.Annotations == nil {
+		ns.Annotations = map[string]string{}
+	}
+
+	ns.Annotations[localGenesisAnnotation] = "true"
+	_, err = config.CLIENTSET.CoreV1().Namespaces().Update(ctx, ns, metav1.UpdateOptions{})
+
+	return err
+}
+
+// hasLocalGenesis reports whether the genesis of the stack was generated by the cli and stored
+func hasLocalGenesis(ctx context.Context, nsArgs string) bool {
+	ns, err := config.CLIENTSET.CoreV1().Namespaces().Get(ctx, nsArgs, metav1.GetOptions{})
+	if err != nil {
+		return false
+	}
+
+	return ns.Annotations[localGenesisAnnotation] == "true"
+}
+
+// newLocalGenesis builds the genesis of the stack from fresh validator secrets generated from random
+func newLocalGenesis(nsArgs string, requestBody ConfigRequest, random io.Reader) (*localGenesis, error) {
+	totalNode, err := strconv.Atoi(requestBody.NumOfNodes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid total node %q: %w", requestBody.NumOfNodes, err)
+	}
+
+	gasLimit, err := strconv.ParseUint(requestBody.GasLimit, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gas limit %q: %w", requestBody.GasLimit, err)
+	}
+
+	epochSize, err := strconv.ParseUint(requestBody.EpochSize, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid epoch size %q: %w", requestBody.EpochSize, err)
+	}
+
+	chainID, err := strconv.ParseInt(requestBody.ChainID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid chain id %q: %w", requestBody.ChainID, err)
+	}
+
+	nodePremine, ok := new(big.Int).SetString(requestBody.NodePremineAmount, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid node premine fund %q", requestBody.NodePremineAmount)
+	}
+
+	alloc := map[common.Address]*big.Int{}
+	addBalance := func(account common.Address, amount *big.Int) {
+		if alloc[account] == nil {
+			alloc[account] = new(big.Int)
+		}
+
+		alloc[account].Add(alloc[account], amount)
+	}
+
+	local := &localGenesis{}
+	extra := istanbulExtra{
+		Validators:     []common.Address{},
+		ProposerSeal:   []byte{},
+		CommittedSeals: [][]byte{},
+	}
+	bootnodes := []string{}
+
+	for i := 1; i <= totalNode; i++ {
+		validator, err := newLocalValidator(random)
+		if err != nil {
+			return nil, err
+		}
+
+		local.Validators = append(local.Validators, validator)
+		extra.Validators = append(extra.Validators, validator.Address)
+		addBalance(validator.Address, nodePremine)
+		bootnodes = append(bootnodes, fmt.Sprintf("/dns4/validator-node%v-svc.%s.svc.cluster.local/tcp/1478/p2p/%s", i, nsArgs, validator.NodeID))
+	}
+
+	for _, premine := range requestBody.Premine {
+		amount, ok := new(big.Int).SetString(premine.Amount, 0)
+		if !ok {
+			return nil, fmt.Errorf("invalid premine amount %q for %s", premine.Amount, premine.Account)
+		}
+
+		addBalance(common.HexToAddress(premine.Account), amount)
+	}
+
+	extraData, err := rlp.EncodeToBytes(extra)
+	if err != nil {
+		return nil, err
+	}
+
+	genesis := genesisChain{
+		Name: requestBody.Name,
+		Genesis: genesisBlock{
+			Nonce:      hexutil.Encode(make([]byte, 8)),
+			Timestamp:  "0x0",
+			ExtraData:  hexutil.Encode(append(make([]byte, istanbulExtraVanity), extraData...)),
+			GasLimit:   hexutil.EncodeUint64(gasLimit),
+			Difficulty: "0x1",
+			MixHash:    istanbulDigest,
+			Alloc:      map[string]genesisAccount{},
+			Number:     "0x0",
+			GasUsed:    "0x0",
+		},
+		Params: genesisChainParams{
+			Forks:   map[string]genesisFork{},
+			ChainID: chainID,
+			Engine: map[string]any{
+				"ibft": map[string]any{
+					"epochSize":      epochSize,
+					"type":           "PoA",
+					"validator_type": "ecdsa",
+				},
+			},
+		},
+		Bootnodes: bootnodes,
+	}
+
+	for account, balance := range alloc {
+		genesis.Genesis.Alloc[account.Hex()] = genesisAccount{Balance: hexutil.EncodeBig(balance)}
+	}
+
+	for _, fork := range genesisForks {
+		genesis.Params.Forks[fork] = genesisFork{}
+	}
+
+	local.Genesis, err = json.MarshalIndent(genesis, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+
+	return local, nil
+}
+
+// newLocalValidator generates the ecdsa validator key and the libp2p network key of a validator
+func newLocalValidator(random io.Reader) (localValidator, error) {
+	validatorKey, err := newLocalKey(random)
+	if err != nil {
+		return localValidator{}, err
+	}
+
+	networkKey, err := newLocalKey(random)
+	if err != nil {
+		return localValidator{}, err
+	}
+
+	validator := localValidator{
+		ValidatorKey: hex.EncodeToString(crypto.FromECDSA(validatorKey)),
+		NetworkKey:   hex.EncodeToString(libp2pKey(crypto.FromECDSA(networkKey))),
+		Address:      crypto.PubkeyToAddress(validatorKey.PublicKey),
+		NodeID:       libp2pPeerID(&networkKey.PublicKey),
+	}
+
+	return validator, nil
+}
+
+// newLocalKey reads a secp256k1 key from random. The bytes are used as they are, so a seeded
+// reader always gives the same keys, ecdsa.GenerateKey does not read from custom readers
+func newLocalKey(random io.Reader) (*ecdsa.PrivateKey, error) {
+	seed := make([]byte, 32)
+
+	for {
+		if _, err := io.ReadFull(random, seed); err != nil {
+			return nil, err
+		}
+
+		// zero and values above the order of the curve are no keys, the next bytes are tried
+		if key, err := crypto.ToECDSA(seed); err == nil {
+			return key, nil
+		}
+	}
+}
+
+// keysJSON returns the public keys of the validator the way polygon-edge secrets init --json prints them
+func (v localValidator) keysJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{
+		"address": v.Address.Hex(),
+		"node_id": v.NodeID,
+	})
+}
+
+// libp2pKey wraps a secp256k1 key into the libp2p protobuf key message
+func libp2pKey(key []byte) []byte {
+	return append([]byte{0x08, libp2pKeySecp256k1, 0x12, byte(len(key))}, key...)
+}
+
+// libp2pPeerID returns the peer id of a secp256k1 network key, the identity multihash of its
+// compressed public key in base58
+func libp2pPeerID(pub *ecdsa.PublicKey) string {
+	key := libp2pKey(crypto.CompressPubkey(pub))
+
+	return base58Encode(append([]byte{0x00, byte(len(key))}, key...))
+}
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+func base58Encode(data []byte) string {
+	number := new(big.Int).SetBytes(data)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+
+	var encoded []byte
+	for number.Sign() > 0 {
+		number.DivMod(number, radix, mod)
+		encoded = append(encoded, base58Alphabet[mod.Int64()])
+	}
+
+	for _, b := range data {
+		if b != 0 {
+			break
+		}
+
+		encoded = append(encoded, base58Alphabet[0])
+	}
+
+	for i, j := 0, len(encoded)-1; i < j; i, j = i+1, j-1 {
+		encoded[i], encoded[j] = encoded[j], encoded[i]
+	}
+
+	return string(encoded)
+}
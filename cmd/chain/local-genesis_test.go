@@ -0,0 +1,143 @@
+package chain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func TestNewLocalGenesis(t *testing.T) {
+	requestBody := ConfigRequest{
+		Name:              "polyedge-test",
+		NumOfNodes:        "2",
+		GasLimit:          "5242880",
+		EpochSize:         "100000",
+		ChainID:           "51001",
+		NodePremineAmount: "1000000000000000000000",
+		Premine: []PremineAllo{
+			{Account: "0x85da99c8a7c2c95964c8efd687e95e632fc533d6", Amount: "0x3635C9ADC5DEA00000"},
+		},
+	}
+
+	local, err := newLocalGenesis("stack", requestBody, rand.New(rand.NewSource(1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var genesis genesisChain
+	if err := json.Unmarshal(local.Genesis, &genesis); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedValidators := []struct {
+		address string
+		nodeID  string
+	}{
+		{"0x20A45360809174bae2C4f94562F30b817910c0d9", "16Uiu2HAmHfqbRmcEG1RBkYjE4TsJcrgV6p6szoXmckFkXfBR5iYz"},
+		{"0xf37e0E98d01CCa55dDfFD47319Ac09F5d7Ea3640", "16Uiu2HAkzD9tEFG83J49C5dEBT3JLfi8pyi2jNQDBgah52ZsnNnL"},
+	}
+
+	if len(local.Validators) != len(expectedValidators) {
+		t.Fatalf("expected %d validators, got %d", len(expectedValidators), len(local.Validators))
+	}
+
+	for i, expected := range expectedValidators {
+		validator := local.Validators[i]
+
+		if validator.Address.Hex() != expected.address {
+			t.Errorf("validator %d: expected address %s, got %s", i+1, expected.address, validator.Address.Hex())
+		}
+
+		if validator.NodeID != expected.nodeID {
+			t.Errorf("validator %d: expected node id %s, got %s", i+1, expected.nodeID, validator.NodeID)
+		}
+
+		// the address belongs to the stored validator key
+		key, err := crypto.HexToECDSA(validator.ValidatorKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if crypto.PubkeyToAddress(key.PublicKey) != validator.Address {
+			t.Errorf("validator %d: address does not match its validator key", i+1)
+		}
+
+		// the network key is a libp2p protobuf secp256k1 key
+		if !strings.HasPrefix(validator.NetworkKey, "08021220") {
+			t.Errorf("validator %d: network key %s is no libp2p secp256k1 key", i+1, validator.NetworkKey)
+		}
+
+		bootnode := fmt.Sprintf("/dns4/validator-node%d-svc.stack.svc.cluster.local/tcp/1478/p2p/%s", i+1, expected.nodeID)
+		if genesis.Bootnodes[i] != bootnode {
+			t.Errorf("validator %d: expected bootnode %s, got %s", i+1, bootnode, genesis.Bootnodes[i])
+		}
+	}
+
+	expectedExtra := "0x" + strings.Repeat("00", istanbulExtraVanity) +
+		"efea9420a45360809174bae2c4f94562f30b817910c0d994f37e0e98d01cca55ddffd47319ac09f5d7ea364080c0c080"
+	if genesis.Genesis.ExtraData != expectedExtra {
+		t.Errorf("expected extra data %s, got %s", expectedExtra, genesis.Genesis.ExtraData)
+	}
+
+	// the extra data decodes to the validators in order
+	var extra istanbulExtra
+	if err := rlp.DecodeBytes(hexutil.MustDecode(genesis.Genesis.ExtraData)[istanbulExtraVanity:], &extra); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(extra.Validators) != 2 || extra.Validators[0] != local.Validators[0].Address || extra.Validators[1] != local.Validators[1].Address {
+		t.Errorf("extra data holds validators %v", extra.Validators)
+	}
+
+	expectedAlloc := map[string]string{
+		"0x20A45360809174bae2C4f94562F30b817910c0d9":                            "0x3635c9adc5dea00000",
+		"0xf37e0E98d01CCa55dDfFD47319Ac09F5d7Ea3640":                            "0x3635c9adc5dea00000",
+		common.HexToAddress("0x85da99c8a7c2c95964c8efd687e95e632fc533d6").Hex(): "0x3635c9adc5dea00000",
+	}
+
+	if len(genesis.Genesis.Alloc) != len(expectedAlloc) {
+		t.Errorf("expected %d alloc accounts, got %d", len(expectedAlloc), len(genesis.Genesis.Alloc))
+	}
+
+	for account, balance := range expectedAlloc {
+		if genesis.Genesis.Alloc[account].Balance != balance {
+			t.Errorf("expected balance %s for %s, got %s", balance, account, genesis.Genesis.Alloc[account].Balance)
+		}
+	}
+
+	if genesis.Params.ChainID != 51001 {
+		t.Errorf("expected chain id 51001, got %d", genesis.Params.ChainID)
+	}
+}
+
+func TestNewLocalGenesisIsDeterministic(t *testing.T) {
+	requestBody := ConfigRequest{
+		Name:              "polyedge-test",
+		NumOfNodes:        "4",
+		GasLimit:          "5242880",
+		EpochSize:         "100000",
+		ChainID:           "51001",
+		NodePremineAmount: "1",
+	}
+
+	first, err := newLocalGenesis("stack", requestBody, rand.New(rand.NewSource(7)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := newLocalGenesis("stack", requestBody, rand.New(rand.NewSource(7)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(first.Genesis) != string(second.Genesis) {
+		t.Error("the same seed gave different genesis files")
+	}
+}
@@ -11,39 +11,19 @@ import (
 
 	"k8s.io/apimachinery/pkg/api/resource"
 	"k8s.io/apimachinery/pkg/util/intstr"
-
-	"cli/cmd/config"
 )
 
-func CreateStorageClassAndPVC(nsArgs string) (string, error) {
-	storage := &storagev1.StorageClass{
-		AllowVolumeExpansion: toGetBooleanPtr(true),
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "StorageClass",
-			APIVersion: "storage.k8s.io/v1",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name: "polygonsc",
-		},
-		Parameters: map[string]string{
-			"type": "pd-standard",
-		},
-		Provisioner:       "kubernetes.io/gce-pd",
-		ReclaimPolicy:     toPVReclaimPolicyPtr("Delete"),
-		VolumeBindingMode: toModePtr(storagev1.VolumeBindingWaitForFirstConsumer),
-	}
-	_, err := config.CLIENTSET.StorageV1().StorageClasses().Create(context.TODO(), storage, metav1.CreateOptions{})
+func CreateStorageClassAndPVC(ctx context.Context, nsArgs string) (string, error) {
+	requestBody, err := loadStackRequest(ctx, nsArgs)
 	if err != nil {
-		// response.StatusCode = http.StatusBadRequest
-		// response.Success = false
-		// response.Message = err.Error()
-		// response.SendResponse(c)
-		// return
+		return "", err
+	}
 
-		// return "", err
+	if err := ensureStorageClass(ctx, requestBody); err != nil {
+		return "", err
 	}
 
-	getParam, err := GetTotalNode(nsArgs)
+	getParam, err := GetTotalNode(ctx, nsArgs)
 
 	if err != nil {
 		return "", err
@@ -52,100 +32,122 @@ func CreateStorageClassAndPVC(nsArgs string) (string, error) {
 	totalNode, err := strconv.Atoi(getParam)
 
 	for i := 1; i <= totalNode; i++ {
-		fsMode := apiv1.PersistentVolumeFilesystem
-		node := fmt.Sprintf("polygon-edge-validator-%v-pvc", i)
+		err := applyPVC(ctx, nsArgs, newValidatorPVC(nsArgs, i, requestBody))
 
-		validatorPVC := &apiv1.PersistentVolumeClaim{
-			TypeMeta: metav1.TypeMeta{
-				Kind:       "PersistentVolumeClaim",
-				APIVersion: "v1",
-			},
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      node,
-				Namespace: nsArgs,
-			},
-			Spec: apiv1.PersistentVolumeClaimSpec{
-				AccessModes: []apiv1.PersistentVolumeAccessMode{
-					"ReadWriteOnce",
-				},
-				StorageClassName: toGetStringPtr("polygonsc"),
-				Resources: apiv1.ResourceRequirements{
-					Requests: apiv1.ResourceList{
-						apiv1.ResourceName(apiv1.ResourceStorage): resource.MustParse("10Gi"),
-					},
-				},
-				VolumeMode: &fsMode,
-			},
+		if err != nil {
+			return "", err
 		}
-		_, err := config.CLIENTSET.CoreV1().PersistentVolumeClaims(nsArgs).Create(context.TODO(), validatorPVC, metav1.CreateOptions{})
+	}
+
+	for i := 1; i <= totalNode; i++ {
+		err := applyService(ctx, nsArgs, newValidatorService(nsArgs, i, requestBody))
 
 		if err != nil {
 			return "", err
 		}
 	}
 
-	for i := 1; i < totalNode; i++ {
-		node := fmt.Sprintf("validator-node%v-svc", i)
+	return "PersistentVolumeClaim & Validator Service is successfully configured 💾", nil
+}
 
-		servicePVC := &apiv1.Service{
-			TypeMeta: metav1.TypeMeta{
-				Kind:       "Service",
-				APIVersion: "v1",
-			},
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      node,
-				Namespace: nsArgs,
+// validatorClaim is the data volume claim of validator i
+func (r ConfigRequest) validatorClaim(i int) string {
+	if claim, ok := r.Claims[i]; ok {
+		return claim
+	}
+
+	return fmt.Sprintf("polygon-edge-validator-%v-pvc", i)
+}
+
+func newValidatorPVC(nsArgs string, i int, requestBody ConfigRequest) *apiv1.PersistentVolumeClaim {
+	fsMode := apiv1.PersistentVolumeFilesystem
+	node := fmt.Sprintf("polygon-edge-validator-%v-pvc", i)
+
+	validatorPVC := &apiv1.PersistentVolumeClaim{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PersistentVolumeClaim",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      node,
+			Namespace: nsArgs,
+		},
+		Spec: apiv1.PersistentVolumeClaimSpec{
+			AccessModes: []apiv1.PersistentVolumeAccessMode{
+				"ReadWriteOnce",
 			},
-			Spec: apiv1.ServiceSpec{
-				Type: apiv1.ServiceTypeClusterIP,
-				Selector: map[string]string{
-					"app":       "polygon-edge-network",
-					"namespace": nsArgs,
+			StorageClassName: toGetStringPtr(requestBody.Storage.ClassName),
+			Resources: apiv1.ResourceRequirements{
+				Requests: apiv1.ResourceList{
+					apiv1.ResourceName(apiv1.ResourceStorage): resource.MustParse(requestBody.Storage.Size),
 				},
-				Ports: []apiv1.ServicePort{
-					{
-						Name:     "grpc",
-						Port:     9632,
-						Protocol: apiv1.ProtocolTCP,
-						TargetPort: intstr.IntOrString{
-							IntVal: 9632,
-						},
+			},
+			VolumeMode: &fsMode,
+		},
+	}
+	return validatorPVC
+}
+
+// newValidatorService is the service of validator i, the bootnode address of the validator and the
+// service name of its statefulset. It only selects the pod of the validator
+func newValidatorService(nsArgs string, i int, requestBody ConfigRequest) *apiv1.Service {
+	node := fmt.Sprintf("validator-node%v-svc", i)
+
+	servicePVC := &apiv1.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      node,
+			Namespace: nsArgs,
+		},
+		Spec: apiv1.ServiceSpec{
+			Type:     apiv1.ServiceTypeClusterIP,
+			Selector: validatorPodLabels(nsArgs, i),
+			Ports: []apiv1.ServicePort{
+				{
+					Name:     "grpc",
+					Port:     9632,
+					Protocol: apiv1.ProtocolTCP,
+					TargetPort: intstr.IntOrString{
+						IntVal: 9632,
 					},
-					{
-						Name:     "jsonrpc",
-						Port:     8545,
-						Protocol: apiv1.ProtocolTCP,
-						TargetPort: intstr.IntOrString{
-							IntVal: 8545,
-						},
+				},
+				{
+					Name:     "jsonrpc",
+					Port:     8545,
+					Protocol: apiv1.ProtocolTCP,
+					TargetPort: intstr.IntOrString{
+						IntVal: 8545,
 					},
-					{
-						Name:     "prometheus",
-						Port:     5001,
-						Protocol: apiv1.ProtocolTCP,
-						TargetPort: intstr.IntOrString{
-							IntVal: 5001,
-						},
+				},
+				{
+					Name:     "prometheus",
+					Port:     5001,
+					Protocol: apiv1.ProtocolTCP,
+					TargetPort: intstr.IntOrString{
+						IntVal: 5001,
 					},
-					{
-						Name:     "libp2p",
-						Port:     1478,
-						Protocol: apiv1.ProtocolTCP,
-						TargetPort: intstr.IntOrString{
-							IntVal: 1478,
-						},
+				},
+				{
+					Name:     "libp2p",
+					Port:     1478,
+					Protocol: apiv1.ProtocolTCP,
+					TargetPort: intstr.IntOrString{
+						IntVal: 1478,
 					},
 				},
 			},
-		}
-		_, err := config.CLIENTSET.CoreV1().Services(nsArgs).Create(context.TODO(), servicePVC, metav1.CreateOptions{})
-
-		if err != nil {
-			return "", err
-		}
+		},
+	}
+	// a headless service resolves to the pod ip, peers can dial it before the pod is ready
+	if requestBody.HeadlessServices {
+		servicePVC.Spec.ClusterIP = apiv1.ClusterIPNone
+		servicePVC.Spec.PublishNotReadyAddresses = true
 	}
 
-	return "PersistentVolumeClaim & Validator Service is successfully configured 💾", nil
+	return servicePVC
 }
 
 func toPVReclaimPolicyPtr(s string) *apiv1.PersistentVolumeReclaimPolicy {
@@ -158,3 +160,5 @@ func toModePtr(m storagev1.VolumeBindingMode) *storagev1.VolumeBindingMode { ret
 func toGetStringPtr(s string) *string { return &s }
 
 func toGetBooleanPtr(s bool) *bool { return &s }
+
+func toGetInt32Ptr(i int32) *int32 { return &i }
@@ -0,0 +1,158 @@
+package chain
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// RenderStack builds every object genesis creates for the stack, in creation
+// order, without talking to the cluster
+func RenderStack(nsArgs string, requestBody ConfigRequest) ([]runtime.Object, error) {
+	totalNode, err := strconv.Atoi(requestBody.NumOfNodes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid total node %q: %w", requestBody.NumOfNodes, err)
+	}
+
+	// the child token is issued by vault while the stack is created, there is nothing to render
+	if requestBody.Vault.ChildToken {
+		return nil, fmt.Errorf("a vault child token can only be issued by genesis against a cluster")
+	}
+
+	// a local genesis is generated together with the validator keys, which are never rendered
+	if requestBody.LocalGenesis {
+		return nil, fmt.Errorf("a local genesis can only be generated by genesis against a cluster")
+	}
+
+	requestBody = requestBody.WithDefaults()
+
+	namespace, err := newNameSpace(nsArgs, requestBody)
+	if err != nil {
+		return nil, err
+	}
+
+	secretsObjects, err := getSecretsBackend(requestBody).objects(nsArgs, requestBody)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []runtime.Object{namespace}
+
+	// the class comes first, the secrets backend may already claim a volume of it
+	if requestBody.Storage.ClassName == storageClass {
+		objects = append(objects, newStorageClass(requestBody))
+	}
+
+	objects = append(objects, secretsObjects...)
+	objects = append(objects, newHelperJob(nsArgs, nsArgs, requestBody))
+
+	for i := 1; i <= totalNode; i++ {
+		objects = append(objects, newNodeConfigMap(nsArgs, i, requestBody))
+	}
+
+	for i := 1; i <= totalNode; i++ {
+		objects = append(objects, newValidatorPVC(nsArgs, i, requestBody))
+	}
+
+	for i := 1; i <= totalNode; i++ {
+		objects = append(objects, newValidatorService(nsArgs, i, requestBody))
+	}
+
+	for i := 1; i <= totalNode; i++ {
+		objects = append(objects, newStatefulSet(nsArgs, nsArgs, i, requestBody))
+	}
+
+	objects = append(objects, newLoadBalancer(nsArgs, requestBody))
+
+	return objects, nil
+}
+
+// MarshalManifests serializes the objects into a single multi-document YAML stream
+func MarshalManifests(objects []runtime.Object) (string, error) {
+	var buffer bytes.Buffer
+
+	for _, object := range objects {
+		data, err := marshalManifest(object)
+		if err != nil {
+			return "", err
+		}
+
+		buffer.WriteString("---\n")
+		buffer.Write(data)
+	}
+
+	return buffer.String(), nil
+}
+
+// marshalManifest serializes an object without the fields only the api server fills, the unset
+// creation timestamps and the status
+func marshalManifest(object runtime.Object) ([]byte, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(object)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(content, "status")
+	pruneCreationTimestamps(content)
+
+	return yaml.Marshal(content)
+}
+
+// pruneCreationTimestamps drops the unset creation timestamps of the object and of its templates
+func pruneCreationTimestamps(content map[string]interface{}) {
+	for key, value := range content {
+		switch value := value.(type) {
+		case map[string]interface{}:
+			pruneCreationTimestamps(value)
+		case []interface{}:
+			for _, item := range value {
+				if item, ok := item.(map[string]interface{}); ok {
+					pruneCreationTimestamps(item)
+				}
+			}
+		case nil:
+			if key == "creationTimestamp" {
+				delete(content, key)
+			}
+		}
+	}
+}
+
+// WriteManifests writes one YAML file per object into dir and returns the written paths
+func WriteManifests(objects []runtime.Object, dir string) ([]string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	files := make([]string, 0, len(objects))
+
+	for i, object := range objects {
+		data, err := marshalManifest(object)
+		if err != nil {
+			return nil, err
+		}
+
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			return nil, err
+		}
+
+		kind := strings.ToLower(object.GetObjectKind().GroupVersionKind().Kind)
+		file := filepath.Join(dir, fmt.Sprintf("%02d-%s-%s.yaml", i+1, kind, accessor.GetName()))
+
+		if err := os.WriteFile(file, data, 0644); err != nil {
+			return nil, err
+		}
+
+		files = append(files, file)
+	}
+
+	return files, nil
+}
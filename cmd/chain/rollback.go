@@ -0,0 +1,59 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+type rollbackStep struct {
+	name string
+	undo func(ctx context.Context) (string, error)
+}
+
+// Rollback records the undo action of every step that touched the cluster,
+// so a failed run can be reverted in reverse order
+type Rollback struct {
+	steps []rollbackStep
+}
+
+func NewRollback() *Rollback {
+	return &Rollback{}
+}
+
+// Add records the undo action for a step. Undo actions must tolerate
+// objects that were never created, as a step may fail halfway through
+func (r *Rollback) Add(name string, undo func(ctx context.Context) (string, error)) {
+	r.steps = append(r.steps, rollbackStep{name: name, undo: undo})
+}
+
+// Run executes the recorded undo actions in reverse order. It keeps going
+// after a failed action and reports every message and error it collected.
+// Every action gets a context of its own, as the run it reverts may have
+// been cancelled by an interrupt
+func (r *Rollback) Run() ([]string, error) {
+	var (
+		messages []string
+		errs     []error
+	)
+
+	for i := len(r.steps) - 1; i >= 0; i-- {
+		step := r.steps[i]
+
+		ctx, cancel := stepContext(context.Background())
+		message, err := step.undo(ctx)
+		cancel()
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rollback of %s failed: %w", step.name, err))
+
+			continue
+		}
+
+		messages = append(messages, message)
+	}
+
+	r.steps = nil
+
+	return messages, errors.Join(errs...)
+}
@@ -0,0 +1,235 @@
+package chain
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"cli/cmd/config"
+)
+
+const (
+	// secretsFilePVC holds the encrypted secrets file shared by the helper job and the validators
+	secretsFilePVC = "polygon-edge-secrets-pvc"
+
+	// secretsFileSize is the size of the secrets file volume
+	secretsFileSize = "64Mi"
+
+	// secretsPassphraseSecret holds the passphrase the secrets file is encrypted with
+	secretsPassphraseSecret = "secrets-file-passphrase"
+
+	// secretsPassphrasePlaceholder stands in for the passphrase in rendered manifests, setup
+	// generates the real one in the cluster
+	secretsPassphrasePlaceholder = "replace-with-secrets-passphrase"
+)
+
+// fileBackend keeps the genesis and the validator secrets in one encrypted file on a volume
+// shared by every pod of the stack. The volume is ReadWriteOnce, so it is meant for single
+// node dev clusters
+type fileBackend struct{}
+
+func (fileBackend) setup(ctx context.Context, nsArgs string, requestBody ConfigRequest) error {
+	// the secrets volume is bound before the validator volumes, so the class has to exist already
+	if err := ensureStorageClass(ctx, requestBody); err != nil {
+		return err
+	}
+
+	// the passphrase of an earlier run is kept, the file may already be encrypted with it
+	_, err := config.CLIENTSET.CoreV1().Secrets(nsArgs).Get(ctx, secretsPassphraseSecret, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		passphrase, err := newSecretsPassphrase()
+		if err != nil {
+			return err
+		}
+
+		if err := applySecret(ctx, nsArgs, newSecretsPassphraseSecret(nsArgs, passphrase)); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	return applyPVC(ctx, nsArgs, newSecretsFilePVC(nsArgs, requestBody))
+}
+
+func (fileBackend) objects(nsArgs string, requestBody ConfigRequest) ([]runtime.Object, error) {
+	return []runtime.Object{
+		newSecretsPassphraseSecret(nsArgs, secretsPassphrasePlaceholder),
+		newSecretsFilePVC(nsArgs, requestBody),
+	}, nil
+}
+
+func (fileBackend) envs(nsArgs string, requestBody ConfigRequest) []apiv1.EnvVar {
+	return []apiv1.EnvVar{
+		{
+			Name: "SECRETS_PASSPHRASE",
+			ValueFrom: &apiv1.EnvVarSource{
+				SecretKeyRef: &apiv1.SecretKeySelector{
+					LocalObjectReference: apiv1.LocalObjectReference{
+						Name: secretsPassphraseSecret,
+					},
+					Key: "passphrase",
+				},
+			},
+		},
+	}
+}
+
+func (fileBackend) serviceAccount(requestBody ConfigRequest) string {
+	return ""
+}
+
+func (fileBackend) jobVolumes() ([]apiv1.Volume, []apiv1.VolumeMount) {
+	return secretsFileVolumes(false)
+}
+
+func (fileBackend) nodeVolumes(i int) ([]apiv1.Volume, []apiv1.VolumeMount) {
+	return secretsFileVolumes(true)
+}
+
+func (fileBackend) jobScript() string {
+	return localSecretsInitScript + `
+store_secrets() {
+  cp /genesis.json /home/genesis.json
+  nodes=$(for i in $(seq 1 $((NUM_OF_NODES))); do echo node${i}; done)
+
+  tar -czf - -C /home genesis.json ${nodes} |
+    openssl enc -aes-256-cbc -pbkdf2 -salt -pass env:SECRETS_PASSPHRASE -out /secrets/secrets.enc
+
+  echo "Secret successfully written the encrypted secrets file!"
+}
+
+# add_secrets adds the nodes to the encrypted file, keeping the genesis and the nodes already in it
+add_secrets() {
+  mkdir -p /tmp/secrets
+  openssl enc -d -aes-256-cbc -pbkdf2 -pass env:SECRETS_PASSPHRASE -in /secrets/secrets.enc | tar -xzf - -C /tmp/secrets
+
+  for i in $(seq $1 $2);
+  do
+    rm -rf /tmp/secrets/node${i}
+    cp -r /home/node${i} /tmp/secrets/
+  done
+
+  # members are listed by name, the init containers extract them by name
+  tar -czf - -C /tmp/secrets $(ls /tmp/secrets) |
+    openssl enc -aes-256-cbc -pbkdf2 -salt -pass env:SECRETS_PASSPHRASE -out /secrets/secrets.enc.new
+  mv /secrets/secrets.enc.new /secrets/secrets.enc
+  rm -rf /tmp/secrets
+
+  echo "Secret successfully added nodes $1 to $2 to the encrypted secrets file!"
+}
+`
+}
+
+func (fileBackend) fetchScript(i int) string {
+	return fmt.Sprintf(`
+mkdir -p /tmp/secrets /data/node%[1]v
+openssl enc -d -aes-256-cbc -pbkdf2 -pass env:SECRETS_PASSPHRASE -in /secrets/secrets.enc |
+  tar -xzf - -C /tmp/secrets genesis.json node%[1]v
+
+cp /tmp/secrets/genesis.json /data/genesis.json
+cp -r /tmp/secrets/node%[1]v/consensus /tmp/secrets/node%[1]v/libp2p /data/node%[1]v/
+rm -rf /tmp/secrets
+`, i)
+}
+
+func (fileBackend) secretsConfig(i int) string {
+	return ""
+}
+
+func (fileBackend) tools() ([]string, []string) {
+	return []string{"openssl"}, []string{"openssl"}
+}
+
+// storeGenesis is not supported, the encrypted file lives on a volume only the helper job writes to
+func (fileBackend) storeGenesis(ctx context.Context, nsArgs string, requestBody ConfigRequest, genesis *localGenesis) error {
+	return fmt.Errorf("a local genesis can not be stored in the %s secrets backend", SecretsBackendFile)
+}
+
+// deleteNodeSecrets is not supported, the secrets of every node share one encrypted file
+func (fileBackend) deleteNodeSecrets(ctx context.Context, nsArgs string, i int) error {
+	return fmt.Errorf("the secrets of node %d can not be removed from the %s secrets backend", i, SecretsBackendFile)
+}
+
+func newSecretsPassphrase() (string, error) {
+	passphrase := make([]byte, 32)
+	if _, err := rand.Read(passphrase); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(passphrase), nil
+}
+
+func newSecretsPassphraseSecret(nsArgs string, passphrase string) *apiv1.Secret {
+	secret := &apiv1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretsPassphraseSecret,
+			Namespace: nsArgs,
+		},
+		Type: apiv1.SecretTypeOpaque,
+		StringData: map[string]string{
+			"passphrase": passphrase,
+		},
+	}
+
+	return secret
+}
+
+func newSecretsFilePVC(nsArgs string, requestBody ConfigRequest) *apiv1.PersistentVolumeClaim {
+	pvc := &apiv1.PersistentVolumeClaim{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PersistentVolumeClaim",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      secretsFilePVC,
+			Namespace: nsArgs,
+		},
+		Spec: apiv1.PersistentVolumeClaimSpec{
+			AccessModes:      []apiv1.PersistentVolumeAccessMode{apiv1.ReadWriteOnce},
+			StorageClassName: toGetStringPtr(requestBody.Storage.ClassName),
+			Resources: apiv1.ResourceRequirements{
+				Requests: apiv1.ResourceList{
+					apiv1.ResourceStorage: resource.MustParse(secretsFileSize),
+				},
+			},
+		},
+	}
+
+	return pvc
+}
+
+func secretsFileVolumes(readOnly bool) ([]apiv1.Volume, []apiv1.VolumeMount) {
+	volumes := []apiv1.Volume{
+		{
+			Name: "secrets-file",
+			VolumeSource: apiv1.VolumeSource{
+				PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{
+					ClaimName: secretsFilePVC,
+					ReadOnly:  readOnly,
+				},
+			},
+		},
+	}
+
+	mounts := []apiv1.VolumeMount{
+		{
+			Name:      "secrets-file",
+			MountPath: "/secrets",
+			ReadOnly:  readOnly,
+		},
+	}
+
+	return volumes, mounts
+}
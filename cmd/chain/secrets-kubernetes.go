@@ -0,0 +1,270 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"cli/cmd/config"
+)
+
+const (
+	// kubernetesSecretsAccount is the service account the helper job writes the secrets with
+	kubernetesSecretsAccount = "polygon-edge-secrets"
+
+	// kubernetesGenesisSecret holds the genesis, validator-node<i>-secrets the keys of every validator
+	kubernetesGenesisSecret = "polygon-edge-genesis"
+)
+
+// kubernetesBackend keeps the genesis and the validator secrets in secrets of the stack namespace
+type kubernetesBackend struct{}
+
+func (kubernetesBackend) setup(ctx context.Context, nsArgs string, requestBody ConfigRequest) error {
+	if err := applyServiceAccount(ctx, nsArgs, newSecretsServiceAccount(nsArgs)); err != nil {
+		return err
+	}
+
+	if err := applyRole(ctx, nsArgs, newSecretsRole(nsArgs)); err != nil {
+		return err
+	}
+
+	return applyRoleBinding(ctx, nsArgs, newSecretsRoleBinding(nsArgs))
+}
+
+func (kubernetesBackend) objects(nsArgs string, requestBody ConfigRequest) ([]runtime.Object, error) {
+	return []runtime.Object{
+		newSecretsServiceAccount(nsArgs),
+		newSecretsRole(nsArgs),
+		newSecretsRoleBinding(nsArgs),
+	}, nil
+}
+
+func (kubernetesBackend) envs(nsArgs string, requestBody ConfigRequest) []apiv1.EnvVar {
+	return nil
+}
+
+func (kubernetesBackend) serviceAccount(requestBody ConfigRequest) string {
+	return kubernetesSecretsAccount
+}
+
+func (kubernetesBackend) jobVolumes() ([]apiv1.Volume, []apiv1.VolumeMount) {
+	return nil, nil
+}
+
+func (kubernetesBackend) nodeVolumes(i int) ([]apiv1.Volume, []apiv1.VolumeMount) {
+	volumes := []apiv1.Volume{
+		{
+			Name: "genesis",
+			VolumeSource: apiv1.VolumeSource{
+				Secret: &apiv1.SecretVolumeSource{
+					SecretName: kubernetesGenesisSecret,
+				},
+			},
+		},
+		{
+			Name: "validator-secrets",
+			VolumeSource: apiv1.VolumeSource{
+				Secret: &apiv1.SecretVolumeSource{
+					SecretName:  fmt.Sprintf("validator-node%v-secrets", i),
+					DefaultMode: toGetInt32Ptr(0400),
+				},
+			},
+		},
+	}
+
+	mounts := []apiv1.VolumeMount{
+		{
+			Name:      "genesis",
+			MountPath: "/genesis",
+			ReadOnly:  true,
+		},
+		{
+			Name:      "validator-secrets",
+			MountPath: "/secrets",
+			ReadOnly:  true,
+		},
+	}
+
+	return volumes, mounts
+}
+
+func (kubernetesBackend) jobScript() string {
+	return localSecretsInitScript + `
+# apply_secret <name> creates or replaces the secret read from stdin through the api server
+apply_secret() {
+  body=$(cat)
+  sa=/var/run/secrets/kubernetes.io/serviceaccount
+  api=https://kubernetes.default.svc/api/v1/namespaces/${NAMESPACE}/secrets
+  auth="Authorization: Bearer $(cat $sa/token)"
+
+  curl --silent --fail --cacert $sa/ca.crt --header "$auth" --header "Content-Type: application/json" \
+    --request POST --data "$body" $api > /dev/null ||
+  curl --silent --fail --cacert $sa/ca.crt --header "$auth" --header "Content-Type: application/json" \
+    --request PUT --data "$body" $api/$1 > /dev/null
+}
+
+store_secrets() {
+  jq -n --arg genesis "$(base64 -w0 /genesis.json)" \
+    '{apiVersion: "v1", kind: "Secret", metadata: {name: "` + kubernetesGenesisSecret + `"}, data: {"genesis.json": $genesis}}' |
+    apply_secret ` + kubernetesGenesisSecret + `
+
+  echo "Secret successfully written genesis.json to Kubernetes!"
+
+  add_secrets 1 ${NUM_OF_NODES}
+}
+
+add_secrets() {
+  for i in $(seq $1 $2);
+  do
+    jq -n --arg name validator-node${i}-secrets \
+      --arg validator "$(base64 -w0 /home/node${i}/consensus/validator.key)" \
+      --arg bls "$(base64 -w0 /home/node${i}/consensus/validator-bls.key)" \
+      --arg libp2p "$(base64 -w0 /home/node${i}/libp2p/libp2p.key)" \
+      '{apiVersion: "v1", kind: "Secret", metadata: {name: $name}, data: {"validator.key": $validator, "validator-bls.key": $bls, "libp2p.key": $libp2p}}' |
+      apply_secret validator-node${i}-secrets
+
+    echo "Secret successfully written node ${i} secrets to Kubernetes!"
+  done
+}
+`
+}
+
+func (kubernetesBackend) fetchScript(i int) string {
+	return fmt.Sprintf(`
+cp /genesis/genesis.json /data/genesis.json
+
+mkdir -p /data/node%[1]v/consensus /data/node%[1]v/libp2p
+cp /secrets/validator.key /data/node%[1]v/consensus/
+cp /secrets/libp2p.key /data/node%[1]v/libp2p/
+
+# a genesis generated by the cli uses ecdsa validators, which have no bls key
+if [ -f /secrets/validator-bls.key ]; then
+  cp /secrets/validator-bls.key /data/node%[1]v/consensus/
+fi
+`, i)
+}
+
+func (kubernetesBackend) secretsConfig(i int) string {
+	return ""
+}
+
+func (kubernetesBackend) tools() ([]string, []string) {
+	return []string{"curl"}, nil
+}
+
+func (kubernetesBackend) storeGenesis(ctx context.Context, nsArgs string, requestBody ConfigRequest, genesis *localGenesis) error {
+	err := applySecret(ctx, nsArgs, newKubernetesSecret(nsArgs, kubernetesGenesisSecret, map[string]string{
+		"genesis.json": string(genesis.Genesis),
+	}))
+	if err != nil {
+		return err
+	}
+
+	for i, validator := range genesis.Validators {
+		err := applySecret(ctx, nsArgs, newKubernetesSecret(nsArgs, fmt.Sprintf("validator-node%v-secrets", i+1), map[string]string{
+			"validator.key": validator.ValidatorKey,
+			"libp2p.key":    validator.NetworkKey,
+		}))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (kubernetesBackend) deleteNodeSecrets(ctx context.Context, nsArgs string, i int) error {
+	err := config.CLIENTSET.CoreV1().Secrets(nsArgs).Delete(ctx, fmt.Sprintf("validator-node%v-secrets", i), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+func newKubernetesSecret(nsArgs string, name string, data map[string]string) *apiv1.Secret {
+	secret := &apiv1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: nsArgs,
+		},
+		Type:       apiv1.SecretTypeOpaque,
+		StringData: data,
+	}
+
+	return secret
+}
+
+func newSecretsServiceAccount(nsArgs string) *apiv1.ServiceAccount {
+	serviceAccount := &apiv1.ServiceAccount{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ServiceAccount",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kubernetesSecretsAccount,
+			Namespace: nsArgs,
+		},
+	}
+
+	return serviceAccount
+}
+
+// newSecretsRole lets the helper job write the secrets of the stack namespace and nothing else
+func newSecretsRole(nsArgs string) *rbacv1.Role {
+	role := &rbacv1.Role{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Role",
+			APIVersion: "rbac.authorization.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kubernetesSecretsAccount,
+			Namespace: nsArgs,
+		},
+		Rules: []rbacv1.PolicyRule{
+			{
+				APIGroups: []string{""},
+				Resources: []string{"secrets"},
+				Verbs:     []string{"create", "get", "update"},
+			},
+		},
+	}
+
+	return role
+}
+
+func newSecretsRoleBinding(nsArgs string) *rbacv1.RoleBinding {
+	roleBinding := &rbacv1.RoleBinding{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "RoleBinding",
+			APIVersion: "rbac.authorization.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kubernetesSecretsAccount,
+			Namespace: nsArgs,
+		},
+		Subjects: []rbacv1.Subject{
+			{
+				Kind:      rbacv1.ServiceAccountKind,
+				Name:      kubernetesSecretsAccount,
+				Namespace: nsArgs,
+			},
+		},
+		RoleRef: rbacv1.RoleRef{
+			APIGroup: rbacv1.GroupName,
+			Kind:     "Role",
+			Name:     kubernetesSecretsAccount,
+		},
+	}
+
+	return roleBinding
+}
@@ -0,0 +1,441 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"cli/cmd/config"
+)
+
+const (
+	// vaultSecretName holds the vault token of the stack
+	vaultSecretName = "vaultconfig-secret"
+
+	// vaultTokenAccessorAnnotation stores the accessor of a per-stack child token so it can be revoked
+	vaultTokenAccessorAnnotation = "polygon-supernet-cli/vault-token-accessor"
+
+	// vaultServiceAccountName is the service account the stack pods log in to vault with
+	vaultServiceAccountName = "polygon-edge-vault"
+
+	// vaultRenewCronJob renews the periodic child token of the stack before its period ends
+	vaultRenewCronJob = "vault-token-renew"
+
+	// vaultTokenPlaceholder stands in for the vault token in rendered manifests, which are meant
+	// to be committed and must never carry the token of the operator
+	vaultTokenPlaceholder = "replace-with-vault-token"
+)
+
+// vaultBackend keeps the genesis and the validator secrets in the polygon-edge and secret KV v2 mounts
+type vaultBackend struct{}
+
+func (vaultBackend) setup(ctx context.Context, nsArgs string, requestBody ConfigRequest) error {
+	return createVaultAccess(ctx, nsArgs, requestBody)
+}
+
+func (vaultBackend) objects(nsArgs string, requestBody ConfigRequest) ([]runtime.Object, error) {
+	// the vault role of the kubernetes auth method is created by genesis, a rendered stack needs it to exist
+	if requestBody.Vault.AuthMethod == VaultAuthKubernetes {
+		return []runtime.Object{newVaultServiceAccount(nsArgs)}, nil
+	}
+
+	return []runtime.Object{newVaultSecret(nsArgs, vaultTokenPlaceholder)}, nil
+}
+
+func (vaultBackend) envs(nsArgs string, requestBody ConfigRequest) []apiv1.EnvVar {
+	return vaultEnvs(nsArgs, requestBody)
+}
+
+func (vaultBackend) serviceAccount(requestBody ConfigRequest) string {
+	return vaultServiceAccount(requestBody)
+}
+
+func (vaultBackend) jobVolumes() ([]apiv1.Volume, []apiv1.VolumeMount) {
+	return nil, nil
+}
+
+func (vaultBackend) nodeVolumes(i int) ([]apiv1.Volume, []apiv1.VolumeMount) {
+	return nil, nil
+}
+
+func (vaultBackend) jobScript() string {
+	return vaultLoginScript + `
+init_secrets() {
+  name=${STACK_ID}/node$1
+  echo "{\"token\": \"${VAULT_TOKEN}\", \"server_url\": \"${VAULT_ADDR}\", \"type\": \"hashicorp-vault\", \"name\": \"$name\"}" > /home/vaultconfignode$1.json
+  polygon-edge secrets init --config /home/vaultconfignode$1.json --json | jq > /home/node$1keys.json
+}
+
+store_secrets() {
+  SECRET_PATH="polygon-edge/data/${STACK_ID}"
+
+  # Create the secret in Vault
+  curl --header "X-Vault-Token: ${VAULT_TOKEN}" \
+  --request POST \
+  --data "{\"data\": $(cat /genesis.json)}" \
+  ${VAULT_ADDR}/v1/${SECRET_PATH}/genesis.json
+
+  echo "Secret successfully written genesis.json to Vault!"
+
+  add_secrets 1 ${NUM_OF_NODES}
+}
+
+add_secrets() {
+  SECRET_PATH="polygon-edge/data/${STACK_ID}"
+
+  for i in $(seq $1 $2);
+  do
+    # Writing public keys to vault in json format
+    curl --header "X-Vault-Token: ${VAULT_TOKEN}" \
+    --request POST \
+    --data "{\"data\": $(cat /home/node${i}keys.json)}" \
+    ${VAULT_ADDR}/v1/${SECRET_PATH}/node${i}/keys.json
+
+    echo "Secret successfully written node ${i} keys json to Vault!"
+
+    # Writing vault secrets config to vault
+    curl --header "X-Vault-Token: ${VAULT_TOKEN}" \
+    --request POST \
+    --data "{\"data\": $(cat /home/vaultconfignode${i}.json)}" \
+    ${VAULT_ADDR}/v1/${SECRET_PATH}/node${i}/vaultsecretsconfig.json
+
+    echo "Secret successfully written node ${i} vault secrets config json to Vault!"
+  done
+}
+`
+}
+
+func (vaultBackend) fetchScript(i int) string {
+	return vaultLoginScript + fmt.Sprintf(`
+SECRET_PATH="polygon-edge/data/${STACK_ID}/genesis.json"
+JSON_FILE_PATH="/data/genesis.json"
+
+curl --header "X-Vault-Token: ${VAULT_TOKEN}" \
+${VAULT_ADDR}/v1/${SECRET_PATH} | jq -r '.data.data' > ${JSON_FILE_PATH}
+
+ls -lrt /data
+
+cat /data/genesis.json
+
+VAULTCONFIG_SECRET_PATH="polygon-edge/data/${STACK_ID}/node%v/vaultsecretsconfig.json"
+VAULTCONFIG_JSON_FILE_PATH="/data/vaultsecretsconfig.json"
+curl --header "X-Vault-Token: ${VAULT_TOKEN}" \
+${VAULT_ADDR}/v1/${VAULTCONFIG_SECRET_PATH} | jq -r '.data.data' > ${VAULTCONFIG_JSON_FILE_PATH}
+
+# the stored secrets config carries the token of the helper job, use the token of this pod instead
+if [ "$VAULT_AUTH_METHOD" = "kubernetes" ]; then
+  jq --arg token "${VAULT_TOKEN}" '.token = $token' ${VAULTCONFIG_JSON_FILE_PATH} > /tmp/vaultsecretsconfig.json
+  mv /tmp/vaultsecretsconfig.json ${VAULTCONFIG_JSON_FILE_PATH}
+fi
+`, i)
+}
+
+func (vaultBackend) secretsConfig(i int) string {
+	return "/data/vaultsecretsconfig.json"
+}
+
+func (vaultBackend) tools() ([]string, []string) {
+	return []string{"curl"}, []string{"jq", "curl"}
+}
+
+// storeGenesis writes the validator secrets where the polygon-edge vault secrets manager reads
+// them, and the genesis, keys and secrets configs where store_secrets of the helper job would
+func (vaultBackend) storeGenesis(ctx context.Context, nsArgs string, requestBody ConfigRequest, genesis *localGenesis) error {
+	token, err := stackVaultToken(ctx, nsArgs, requestBody)
+	if err != nil {
+		return err
+	}
+
+	if err := writeVaultSecret(vaultStackMount, nsArgs+"/genesis.json", json.RawMessage(genesis.Genesis)); err != nil {
+		return err
+	}
+
+	for i, validator := range genesis.Validators {
+		name := fmt.Sprintf("%s/node%v", nsArgs, i+1)
+
+		secrets := map[string]string{
+			"validator-key": validator.ValidatorKey,
+			"network-key":   validator.NetworkKey,
+		}
+
+		for key, value := range secrets {
+			if err := writeVaultSecret(vaultSecretsMount, name+"/"+key, map[string]string{key: value}); err != nil {
+				return err
+			}
+		}
+
+		keys, err := validator.keysJSON()
+		if err != nil {
+			return err
+		}
+
+		if err := writeVaultSecret(vaultStackMount, name+"/keys.json", json.RawMessage(keys)); err != nil {
+			return err
+		}
+
+		// with the kubernetes auth method the token is replaced by the login token of the pod
+		secretsConfig := map[string]string{
+			"token":      token,
+			"server_url": config.VaultUrl,
+			"type":       "hashicorp-vault",
+			"name":       name,
+		}
+
+		if err := writeVaultSecret(vaultStackMount, name+"/vaultsecretsconfig.json", secretsConfig); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteNodeSecrets purges the validator secrets and the keys and secrets config of node i
+func (vaultBackend) deleteNodeSecrets(ctx context.Context, nsArgs string, i int) error {
+	if !IsVaultConfigured() {
+		return fmt.Errorf("vault url and token are required to purge secrets")
+	}
+
+	for _, mount := range []string{vaultStackMount, vaultSecretsMount} {
+		if err := purgeVaultPath(mount, fmt.Sprintf("%s/node%v", nsArgs, i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// vaultLoginScript exchanges the service account token of the pod for a vault token when
+// the stack uses the kubernetes auth method
+const vaultLoginScript = `
+if [ "$VAULT_AUTH_METHOD" = "kubernetes" ]; then
+  jwt=$(cat /var/run/secrets/kubernetes.io/serviceaccount/token)
+  VAULT_TOKEN=$(curl --silent --fail --request POST \
+    --data "{\"role\": \"${VAULT_ROLE}\", \"jwt\": \"${jwt}\"}" \
+    ${VAULT_ADDR}/v1/auth/${VAULT_AUTH_MOUNT}/login | jq -r .auth.client_token)
+fi
+`
+
+// createVaultAccess gives the stack access to vault. With the kubernetes auth method a service
+// account and a vault role bound to it are created, otherwise the token is stored in a secret.
+// With a child token the secret of an earlier run is kept, so resuming does not issue a second token
+func createVaultAccess(ctx context.Context, nsArgs string, requestBody ConfigRequest) error {
+	if requestBody.Vault.AuthMethod == VaultAuthKubernetes {
+		if err := applyServiceAccount(ctx, nsArgs, newVaultServiceAccount(nsArgs)); err != nil {
+			return err
+		}
+
+		return CreateStackVaultRole(nsArgs, requestBody.Vault.AuthMount, requestBody.Vault.TokenTTL)
+	}
+
+	if !requestBody.Vault.ChildToken {
+		return applySecret(ctx, nsArgs, newVaultSecret(nsArgs, config.VaultToken))
+	}
+
+	_, err := config.CLIENTSET.CoreV1().Secrets(nsArgs).Get(ctx, vaultSecretName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		token, accessor, err := CreateStackVaultToken(nsArgs, requestBody.Vault.TokenTTL)
+		if err != nil {
+			return err
+		}
+
+		secret := newVaultSecret(nsArgs, token)
+		secret.Annotations = map[string]string{
+			vaultTokenAccessorAnnotation: accessor,
+		}
+
+		if err := applySecret(ctx, nsArgs, secret); err != nil {
+			_ = revokeVaultToken(nsArgs, accessor)
+
+			return err
+		}
+	} else if err != nil {
+		return err
+	}
+
+	// the periodic token expires once a period passes without a renewal, validators restarted
+	// after that could no longer fetch their secrets
+	return applyCronJob(ctx, nsArgs, newVaultRenewCronJob(nsArgs, requestBody))
+}
+
+// newVaultRenewCronJob renews the child token of the stack well within its period
+func newVaultRenewCronJob(nsArgs string, requestBody ConfigRequest) *batchv1.CronJob {
+	var backoffLimit int32 = 3
+
+	script := requestBody.Images.installScript([]string{"curl"}) + `
+curl --silent --show-error --fail --request POST --header "X-Vault-Token: ${VAULT_TOKEN}" \
+  ${VAULT_ADDR}/v1/auth/token/renew-self > /dev/null
+
+echo "Vault token successfully renewed!"
+`
+
+	cronJob := &batchv1.CronJob{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "CronJob",
+			APIVersion: "batch/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      vaultRenewCronJob,
+			Namespace: nsArgs,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:          vaultRenewSchedule(requestBody.Vault.TokenTTL),
+			ConcurrencyPolicy: batchv1.ForbidConcurrent,
+			JobTemplate: batchv1.JobTemplateSpec{
+				Spec: batchv1.JobSpec{
+					BackoffLimit: &backoffLimit,
+					Template: apiv1.PodTemplateSpec{
+						Spec: apiv1.PodSpec{
+							RestartPolicy:    apiv1.RestartPolicyOnFailure,
+							ImagePullSecrets: requestBody.Images.pullSecrets(),
+							Containers: []apiv1.Container{
+								{
+									Name:    vaultRenewCronJob,
+									Image:   requestBody.Images.image(requestBody.Images.Fetch),
+									Env:     vaultEnvs(nsArgs, requestBody),
+									Command: []string{"/bin/sh", "-c"},
+									Args:    []string{script},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	return cronJob
+}
+
+// vaultRenewSchedule renews a token at most half way through its period, periods are validated
+// to be at least vaultMinTokenPeriod
+func vaultRenewSchedule(period string) string {
+	duration, _ := time.ParseDuration(period)
+
+	switch {
+	case duration >= 48*time.Hour:
+		return "0 0 * * *"
+	case duration >= 2*time.Hour:
+		return "0 * * * *"
+	default:
+		return "*/10 * * * *"
+	}
+}
+
+// stackVaultToken returns the token the validators of the stack use, empty when they log in
+// with the kubernetes auth method
+func stackVaultToken(ctx context.Context, nsArgs string, requestBody ConfigRequest) (string, error) {
+	if requestBody.Vault.AuthMethod == VaultAuthKubernetes {
+		return "", nil
+	}
+
+	secret, err := config.CLIENTSET.CoreV1().Secrets(nsArgs).Get(ctx, vaultSecretName, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	return string(secret.Data["token"]), nil
+}
+
+// getVaultTokenAccessor returns the accessor of the child token of the stack, empty when it uses the operator token
+func getVaultTokenAccessor(ctx context.Context, nsArgs string) (string, error) {
+	secret, err := config.CLIENTSET.CoreV1().Secrets(nsArgs).Get(ctx, vaultSecretName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	return secret.Annotations[vaultTokenAccessorAnnotation], nil
+}
+
+func newVaultSecret(nsArgs string, token string) *apiv1.Secret {
+	secretData := make(map[string]string)
+	secretData["token"] = token
+
+	secret := &apiv1.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      vaultSecretName,
+			Namespace: nsArgs,
+		},
+		Type:       apiv1.SecretTypeOpaque,
+		StringData: secretData,
+	}
+
+	return secret
+}
+
+func newVaultServiceAccount(nsArgs string) *apiv1.ServiceAccount {
+	serviceAccount := &apiv1.ServiceAccount{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ServiceAccount",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      vaultServiceAccountName,
+			Namespace: nsArgs,
+		},
+	}
+
+	return serviceAccount
+}
+
+// vaultEnvs points the pod at vault. The token is read from the stack secret, or with the
+// kubernetes auth method obtained by vaultLoginScript
+func vaultEnvs(nsArgs string, requestBody ConfigRequest) []apiv1.EnvVar {
+	envs := []apiv1.EnvVar{
+		{
+			Name:  "VAULT_ADDR",
+			Value: config.VaultUrl,
+		},
+	}
+
+	if requestBody.Vault.AuthMethod == VaultAuthKubernetes {
+		return append(envs,
+			apiv1.EnvVar{
+				Name:  "VAULT_AUTH_METHOD",
+				Value: VaultAuthKubernetes,
+			},
+			apiv1.EnvVar{
+				Name:  "VAULT_AUTH_MOUNT",
+				Value: requestBody.Vault.AuthMount,
+			},
+			apiv1.EnvVar{
+				Name:  "VAULT_ROLE",
+				Value: stackVaultPolicyName(nsArgs),
+			},
+		)
+	}
+
+	return append(envs, apiv1.EnvVar{
+		Name: "VAULT_TOKEN",
+		ValueFrom: &apiv1.EnvVarSource{
+			SecretKeyRef: &apiv1.SecretKeySelector{
+				LocalObjectReference: apiv1.LocalObjectReference{
+					Name: vaultSecretName,
+				},
+				Key: "token",
+			},
+		},
+	})
+}
+
+// vaultServiceAccount returns the service account of the stack pods, the default one unless
+// they log in with the kubernetes auth method
+func vaultServiceAccount(requestBody ConfigRequest) string {
+	if requestBody.Vault.AuthMethod == VaultAuthKubernetes {
+		return vaultServiceAccountName
+	}
+
+	return ""
+}
@@ -0,0 +1,85 @@
+package chain
+
+import (
+	"context"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	SecretsBackendVault      = "vault"
+	SecretsBackendKubernetes = "kubernetes"
+	SecretsBackendFile       = "file"
+)
+
+// secretsBackend stores the genesis and the validator secrets generated by the helper job
+// and hands them to the validators
+type secretsBackend interface {
+	// setup prepares the backend before the helper job runs, it is safe to re-run
+	setup(ctx context.Context, nsArgs string, requestBody ConfigRequest) error
+
+	// objects are the namespace objects setup creates, used when the stack is rendered
+	objects(nsArgs string, requestBody ConfigRequest) ([]runtime.Object, error)
+
+	// envs, serviceAccount and volumes configure the helper job and the validator init containers
+	envs(nsArgs string, requestBody ConfigRequest) []apiv1.EnvVar
+	serviceAccount(requestBody ConfigRequest) string
+	jobVolumes() ([]apiv1.Volume, []apiv1.VolumeMount)
+	nodeVolumes(i int) ([]apiv1.Volume, []apiv1.VolumeMount)
+
+	// jobScript defines the init_secrets <node>, store_secrets and add_secrets <first> <last> shell
+	// functions of the helper job, add_secrets stores the secrets of nodes added to a running stack
+	jobScript() string
+
+	// fetchScript places the genesis and the secrets of validator i on its data volume
+	fetchScript(i int) string
+
+	// secretsConfig is the secrets_config of validator i, empty when the secrets are kept in its data dir
+	secretsConfig(i int) string
+
+	// tools are the commands the job script and the fetch script use beyond the busybox shell
+	tools() (job []string, fetch []string)
+
+	// storeGenesis stores a genesis generated by the cli, in place of the store_secrets of the helper job
+	storeGenesis(ctx context.Context, nsArgs string, requestBody ConfigRequest, genesis *localGenesis) error
+
+	// deleteNodeSecrets removes the secrets of validator i once it left the stack
+	deleteNodeSecrets(ctx context.Context, nsArgs string, i int) error
+}
+
+var secretsBackends = map[string]secretsBackend{
+	SecretsBackendVault:      vaultBackend{},
+	SecretsBackendKubernetes: kubernetesBackend{},
+	SecretsBackendFile:       fileBackend{},
+}
+
+// getSecretsBackend returns the backend of the request, vault for stacks created before backends were selectable
+func getSecretsBackend(requestBody ConfigRequest) secretsBackend {
+	if backend, ok := secretsBackends[requestBody.SecretsBackend]; ok {
+		return backend
+	}
+
+	return vaultBackend{}
+}
+
+// UsesVault reports whether the stack keeps its secrets in vault, a stack whose namespace was
+// never created keeps none
+func UsesVault(ctx context.Context, nsArgs string) (bool, error) {
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return requestBody.SecretsBackend == SecretsBackendVault, nil
+}
+
+// localSecretsInitScript generates the validator secrets into a local data dir, for backends
+// that store the files themselves
+const localSecretsInitScript = `
+init_secrets() {
+  polygon-edge secrets init --data-dir /home/node$1 --json | jq > /home/node$1keys.json
+}
+`
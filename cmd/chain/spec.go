@@ -0,0 +1,484 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	StackSpecVersion = "polygon-supernet-cli/v1"
+	StackSpecKind    = "Stack"
+)
+
+type StorageConfig struct {
+	// ClassName is an existing class, or polygonsc which is created from Preset
+	ClassName string `json:"className,omitempty"`
+	Preset    string `json:"preset,omitempty"`
+	Size      string `json:"size,omitempty"`
+}
+
+type ResourceConfig struct {
+	CPURequest    string `json:"cpuRequest,omitempty"`
+	MemoryRequest string `json:"memoryRequest,omitempty"`
+	CPULimit      string `json:"cpuLimit,omitempty"`
+	MemoryLimit   string `json:"memoryLimit,omitempty"`
+}
+
+// VaultConfig controls how the stack authenticates to vault
+type VaultConfig struct {
+	// AuthMethod is token, the default, or kubernetes to log in with the pod service account
+	AuthMethod string `json:"authMethod,omitempty"`
+	AuthMount  string `json:"authMount,omitempty"`
+
+	// ChildToken issues a scoped child token for the stack instead of handing it the operator token
+	ChildToken bool   `json:"childToken,omitempty"`
+	TokenTTL   string `json:"tokenTTL,omitempty"`
+}
+
+// StackSpec is the versioned file format accepted by genesis --file
+type StackSpec struct {
+	APIVersion string        `json:"apiVersion"`
+	Kind       string        `json:"kind"`
+	Spec       ConfigRequest `json:"spec"`
+}
+
+// LoadStackSpec reads a YAML or JSON stack spec, rejecting unknown fields and versions
+func LoadStackSpec(file string) (*StackSpec, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var spec StackSpec
+	if err := yaml.UnmarshalStrict(data, &spec); err != nil {
+		return nil, fmt.Errorf("invalid stack spec %s: %w", file, err)
+	}
+
+	if spec.APIVersion != StackSpecVersion {
+		return nil, fmt.Errorf("unsupported stack spec apiVersion %q, expected %q", spec.APIVersion, StackSpecVersion)
+	}
+
+	if spec.Kind != StackSpecKind {
+		return nil, fmt.Errorf("unsupported stack spec kind %q, expected %q", spec.Kind, StackSpecKind)
+	}
+
+	return &spec, nil
+}
+
+// WithDefaults fills the infrastructure settings that were left empty
+func (r ConfigRequest) WithDefaults() ConfigRequest {
+	if r.Image == "" {
+		r.Image = nodeImage
+	}
+
+	if r.Images.Helper == "" {
+		r.Images.Helper = r.Image
+	}
+
+	if r.Images.Fetch == "" {
+		r.Images.Fetch = fetchImage
+	}
+
+	if r.Storage.ClassName == "" {
+		r.Storage.ClassName = storageClass
+	}
+
+	if r.Storage.Size == "" {
+		r.Storage.Size = storageSize
+	}
+
+	if r.Storage.Preset == "" && r.Storage.ClassName == storageClass {
+		r.Storage.Preset = StoragePresetGCE
+	}
+
+	if r.ServiceType == "" {
+		r.ServiceType = serviceType
+	}
+
+	if r.Consensus == "" {
+		r.Consensus = ConsensusIBFT
+	}
+
+	if r.BlockConfirmation == "" {
+		r.BlockConfirmation = blockConfirmation
+	}
+
+	if r.ChainID == "" {
+		r.ChainID = chainId
+	}
+
+	if r.SecretsBackend == "" {
+		r.SecretsBackend = SecretsBackendVault
+	}
+
+	if r.Vault.ChildToken && r.Vault.TokenTTL == "" {
+		r.Vault.TokenTTL = vaultTokenTTL
+	}
+
+	if r.Vault.AuthMethod == VaultAuthKubernetes {
+		if r.Vault.AuthMount == "" {
+			r.Vault.AuthMount = vaultAuthMount
+		}
+
+		if r.Vault.TokenTTL == "" {
+			r.Vault.TokenTTL = vaultLoginTTL
+		}
+	}
+
+	return r
+}
+
+// Validate checks every field of the request against the stack spec schema
+func (r ConfigRequest) Validate() error {
+	if r.Name == "" {
+		return errors.New("Chain name is required")
+	}
+
+	if err := validatePositiveInt("totalNode", r.NumOfNodes); err != nil {
+		return err
+	}
+
+	if err := validatePositiveInt("gasLimit", r.GasLimit); err != nil {
+		return err
+	}
+
+	if err := validatePositiveInt("epochSize", r.EpochSize); err != nil {
+		return err
+	}
+
+	if r.ChainID != "" {
+		if err := validatePositiveInt("chainId", r.ChainID); err != nil {
+			return err
+		}
+	}
+
+	if _, ok := new(big.Int).SetString(r.NodePremineAmount, 10); !ok {
+		return fmt.Errorf("nodePremineFund must be a decimal amount, got %q", r.NodePremineAmount)
+	}
+
+	for _, premine := range r.Premine {
+		if !common.IsHexAddress(premine.Account) {
+			return fmt.Errorf("The Ethereum address %s is invalid.", premine.Account)
+		}
+
+		if _, ok := new(big.Int).SetString(premine.Amount, 0); !ok {
+			return fmt.Errorf("invalid premine amount %q for %s", premine.Amount, premine.Account)
+		}
+	}
+
+	quantities := map[string]string{
+		"storage.size":            r.Storage.Size,
+		"resources.cpuRequest":    r.Resources.CPURequest,
+		"resources.memoryRequest": r.Resources.MemoryRequest,
+		"resources.cpuLimit":      r.Resources.CPULimit,
+		"resources.memoryLimit":   r.Resources.MemoryLimit,
+	}
+
+	for field, value := range quantities {
+		if value == "" {
+			continue
+		}
+
+		if _, err := resource.ParseQuantity(value); err != nil {
+			return fmt.Errorf("invalid %s %q: %w", field, value, err)
+		}
+	}
+
+	switch r.Consensus {
+	case "", ConsensusIBFT:
+		if r.ValidatorStake != "" || r.BridgeJSONRPC != "" {
+			return errors.New("validatorStake and bridgeJsonRpc are only supported by polybft consensus")
+		}
+	case ConsensusPolyBFT:
+		// the default image has no polybft genesis, the helper job would only fail once the stack exists
+		if r.Image == "" || r.Image == nodeImage {
+			return fmt.Errorf("polybft consensus needs an image whose secrets init prints bls signatures, %s does not", nodeImage)
+		}
+
+		if r.ValidatorStake != "" {
+			if _, ok := new(big.Int).SetString(r.ValidatorStake, 10); !ok {
+				return fmt.Errorf("validatorStake must be a decimal amount, got %q", r.ValidatorStake)
+			}
+		}
+	default:
+		return fmt.Errorf("consensus must be %s or %s, got %q", ConsensusIBFT, ConsensusPolyBFT, r.Consensus)
+	}
+
+	if r.BlockConfirmation != "" {
+		if number, err := strconv.Atoi(r.BlockConfirmation); err != nil || number < 0 {
+			return fmt.Errorf("numBlockConfirmations must be a non negative number, got %q", r.BlockConfirmation)
+		}
+	}
+
+	switch r.SecretsBackend {
+	case "", SecretsBackendVault:
+	case SecretsBackendKubernetes, SecretsBackendFile:
+		if r.Vault != (VaultConfig{}) {
+			return fmt.Errorf("vault settings are only used with the %s secrets backend", SecretsBackendVault)
+		}
+	default:
+		return fmt.Errorf("secretsBackend must be %s, %s or %s, got %q",
+			SecretsBackendVault, SecretsBackendKubernetes, SecretsBackendFile, r.SecretsBackend)
+	}
+
+	if r.Images.AirGapped {
+		jobTools, fetchTools := getSecretsBackend(r).tools()
+
+		if r.Images.Helper == "" && !r.LocalGenesis {
+			return fmt.Errorf("images.helper shipping jq and %s is required in air-gapped mode", strings.Join(jobTools, " and "))
+		}
+
+		if r.Images.Fetch == "" && len(fetchTools) > 0 {
+			return fmt.Errorf("images.fetch shipping %s is required in air-gapped mode", strings.Join(fetchTools, " and "))
+		}
+	}
+
+	for _, reference := range r.Images.PullSecrets {
+		if namespace, name := splitPullSecret(reference); namespace == "" || name == "" || strings.Count(reference, "/") > 1 {
+			return fmt.Errorf("images.pullSecrets entries must be [namespace/]name, got %q", reference)
+		}
+	}
+
+	// the cli only assembles ibft genesis files with ecdsa validators, polybft needs bls keys
+	if r.LocalGenesis {
+		if r.Consensus == ConsensusPolyBFT {
+			return fmt.Errorf("localGenesis is only supported by %s consensus", ConsensusIBFT)
+		}
+
+		if r.SecretsBackend == SecretsBackendFile {
+			return fmt.Errorf("localGenesis can not be combined with the %s secrets backend", SecretsBackendFile)
+		}
+	}
+
+	switch r.Vault.AuthMethod {
+	case "", VaultAuthToken:
+		if r.Vault.AuthMount != "" {
+			return errors.New("vault.authMount is only used with the kubernetes auth method")
+		}
+	case VaultAuthKubernetes:
+		if r.Vault.ChildToken {
+			return errors.New("vault.childToken can not be combined with the kubernetes auth method")
+		}
+	default:
+		return fmt.Errorf("vault.authMethod must be %s or %s, got %q", VaultAuthToken, VaultAuthKubernetes, r.Vault.AuthMethod)
+	}
+
+	if r.Vault.TokenTTL != "" {
+		if !r.Vault.ChildToken && r.Vault.AuthMethod != VaultAuthKubernetes {
+			return errors.New("vault.tokenTTL is only used with vault.childToken or the kubernetes auth method")
+		}
+
+		ttl, err := time.ParseDuration(r.Vault.TokenTTL)
+		if err != nil || ttl <= 0 {
+			return fmt.Errorf("vault.tokenTTL must be a positive duration, got %q", r.Vault.TokenTTL)
+		}
+
+		// the child token is renewed every 10 minutes at most, a shorter period would expire first
+		if r.Vault.ChildToken && ttl < vaultMinTokenPeriod {
+			return fmt.Errorf("vault.tokenTTL is the renewal period of the child token and must be at least %s, got %q", vaultMinTokenPeriod, r.Vault.TokenTTL)
+		}
+	}
+
+	if r.Storage.Preset != "" {
+		if _, ok := storagePresets[r.Storage.Preset]; !ok {
+			return fmt.Errorf("storage.preset must be one of %s, got %q", strings.Join(StoragePresets(), ", "), r.Storage.Preset)
+		}
+
+		if r.Storage.ClassName != "" && r.Storage.ClassName != storageClass {
+			return fmt.Errorf("storage.preset only applies to the %s class created by the cli, %s is used as is", storageClass, r.Storage.ClassName)
+		}
+	}
+
+	switch apiv1.ServiceType(r.ServiceType) {
+	case "", apiv1.ServiceTypeLoadBalancer, apiv1.ServiceTypeNodePort, apiv1.ServiceTypeClusterIP:
+	default:
+		return fmt.Errorf("serviceType must be LoadBalancer, NodePort or ClusterIP, got %q", r.ServiceType)
+	}
+
+	return nil
+}
+
+func validatePositiveInt(field string, value string) error {
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 1 {
+		return fmt.Errorf("%s must be a positive number, got %q", field, value)
+	}
+
+	return nil
+}
+
+// requirements converts the configured resources into container resource requirements
+func (r ResourceConfig) requirements() apiv1.ResourceRequirements {
+	requirements := apiv1.ResourceRequirements{}
+
+	add := func(list *apiv1.ResourceList, name apiv1.ResourceName, value string) {
+		if value == "" {
+			return
+		}
+
+		if *list == nil {
+			*list = apiv1.ResourceList{}
+		}
+
+		(*list)[name] = resource.MustParse(value)
+	}
+
+	add(&requirements.Requests, apiv1.ResourceCPU, r.CPURequest)
+	add(&requirements.Requests, apiv1.ResourceMemory, r.MemoryRequest)
+	add(&requirements.Limits, apiv1.ResourceCPU, r.CPULimit)
+	add(&requirements.Limits, apiv1.ResourceMemory, r.MemoryLimit)
+
+	return requirements
+}
+
+// errNoStackRequest is returned for stacks created before the request was stored
+var errNoStackRequest = errors.New("no stored genesis request")
+
+// loadStackRequest returns the stored request of the stack with defaults applied and the claims
+// of its state. Stacks created before the request was stored fall back to the defaults, a
+// request which can not be read is an error
+func loadStackRequest(ctx context.Context, nsArgs string) (ConfigRequest, error) {
+	requestBody, err := GetStackRequest(ctx, nsArgs)
+	if errors.Is(err, errNoStackRequest) {
+		requestBody = ConfigRequest{}
+		requestBody.NumOfNodes, err = GetTotalNode(ctx, nsArgs)
+	}
+
+	if err != nil {
+		return requestBody, err
+	}
+
+	state, err := loadStackState(ctx, nsArgs)
+	if err != nil {
+		return requestBody, err
+	}
+
+	requestBody.Claims = state.Claims
+
+	return requestBody.WithDefaults(), nil
+}
+
+// updateStackRequest changes the stored request of the stack. A stack created before the request
+// was stored gets its defaults stored
+func updateStackRequest(ctx context.Context, nsArgs string, update func(*ConfigRequest)) error {
+	requestBody, err := GetStackRequest(ctx, nsArgs)
+	if errors.Is(err, errNoStackRequest) {
+		requestBody, err = loadStackRequest(ctx, nsArgs)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	update(&requestBody)
+
+	return storeStackRequest(ctx, nsArgs, requestBody)
+}
+
+// StackSpecTemplate is the annotated template written by spec init
+const StackSpecTemplate = `# Stack spec for "genesis --file". Every field can be overridden by the matching genesis flag.
+apiVersion: polygon-supernet-cli/v1
+kind: Stack
+spec:
+  # the name for the network
+  name: polyedge-local
+
+  # number of total validator nodes, quoted as all chain values are strings
+  totalNode: "4"
+
+  # the maximum amount of gas used by all transactions in a block
+  gasLimit: "10000000"
+
+  # the epoch size for the network
+  epochSize: "10"
+
+  # the chain id, must be unique among the stacks on the cluster
+  chainId: "51001"
+
+  # the premine amount for every validator account
+  nodePremineFund: "1000000000000000"
+
+  # additional premined accounts and balances
+  premine:
+    - account: "0x0000000000000000000000000000000000000000"
+      amount: "1000000000000000000"
+
+  # the polygon-edge image run by the helper job and the validators
+  image: 0xpolygon/polygon-edge:0.9.0
+
+  # the images of the helper job and of the init container fetching the validator secrets, the
+  # helper runs the node image by default. Tools missing from them are installed with apk at runtime
+  # images:
+  #   helper: registry.example.com/polygon-edge-tools:0.9.0
+  #   fetch: alpine:3.18
+  #   # prefixes every image of the stack, including the node image
+  #   registry: registry.example.com
+  #   # [namespace/]name of docker registry secrets, copied into the stack namespace
+  #   pullSecrets:
+  #     - default/registry-credentials
+  #   # never install tools at runtime, for clusters without egress
+  #   airGapped: true
+
+  # the validator data volumes. The polygonsc class is created from the preset, gce, ebs,
+  # azure-disk or local-path. Set className to an existing class to use it instead
+  storage:
+    className: polygonsc
+    preset: gce
+    size: 10Gi
+
+  # resources of every validator container, omit a field to leave it unset
+  resources:
+    cpuRequest: 500m
+    memoryRequest: 1Gi
+    cpuLimit: "2"
+    memoryLimit: 4Gi
+
+  # the type of the polygon-edge-svc service: LoadBalancer, NodePort or ClusterIP
+  serviceType: LoadBalancer
+
+  # the validator-node<i>-svc services resolve to the pod of their validator instead of a cluster IP
+  # headlessServices: true
+
+  # the consensus engine: ibft or polybft, polybft needs an image whose secrets init prints bls signatures
+  consensus: ibft
+
+  # polybft only: the stake of every validator
+  # validatorStake: "1000000000000000000"
+
+  # polybft only: the rootchain json-rpc endpoint, enables the relayer on validator 1
+  # bridgeJsonRpc: http://rootchain:8545
+
+  # the number of rootchain blocks the relayer waits for before processing events
+  numBlockConfirmations: "64"
+
+  # where the genesis and the validator secrets are kept: vault, kubernetes secrets of the stack
+  # namespace, or file, an encrypted file on a volume shared by all pods (single node dev clusters)
+  secretsBackend: vault
+
+  # ibft only: generate the validator keys and the genesis in the cli instead of a helper job,
+  # the validators use ecdsa keys. Not supported by the file secrets backend
+  # localGenesis: true
+
+  # the vault token handed to the stack, by default the operator token is stored in the stack secret
+  # vault:
+  #   # issue a child token limited to the stack secrets instead
+  #   childToken: true
+  #   # or let the pods log in with their service account, no token is stored in the cluster
+  #   authMethod: kubernetes
+  #   # the path the kubernetes auth method is enabled at
+  #   authMount: kubernetes
+  #   # the renewal period of the child token (768h), a cron job of the stack renews it, or the
+  #   # lifetime of the login tokens (1h)
+  #   tokenTTL: 768h
+`
@@ -0,0 +1,79 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"cli/cmd/config"
+)
+
+// stackStateAnnotation stores what the cli tracks about a running stack on its namespace
+const stackStateAnnotation = "polygon-supernet-cli/state"
+
+// stackState is what the cli records about a stack while changing it. It is kept apart from the
+// genesis request, so a stack spec can never set it
+type stackState struct {
+	// Claims are the data volume claims of validators restored from a backup, by node index
+	Claims map[int]string `json:"claims,omitempty"`
+	// KeptClaims are the data volume claims removed validators kept, destroy removes them
+	KeptClaims []string `json:"keptClaims,omitempty"`
+	// PendingRemovals were voted out of the validator set but their nodes were not removed yet
+	PendingRemovals []int `json:"pendingRemovals,omitempty"`
+	// PendingValidators were added to the stack but not voted into the validator set yet
+	PendingValidators []ValidatorKeys `json:"pendingValidators,omitempty"`
+}
+
+// loadStackState returns the recorded state of the stack, a stack without one has an empty state
+func loadStackState(ctx context.Context, nsArgs string) (stackState, error) {
+	var state stackState
+
+	ns, err := config.CLIENTSET.CoreV1().Namespaces().Get(ctx, nsArgs, metav1.GetOptions{})
+	if err != nil {
+		return state, err
+	}
+
+	data, ok := ns.Annotations[stackStateAnnotation]
+	if !ok {
+		return state, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return state, fmt.Errorf("invalid stack state on namespace %s: %w", nsArgs, err)
+	}
+
+	return state, nil
+}
+
+// updateStackState changes the recorded state of the stack
+func updateStackState(ctx context.Context, nsArgs string, update func(*stackState)) error {
+	ns, err := config.CLIENTSET.CoreV1().Namespaces().Get(ctx, nsArgs, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	var state stackState
+	if data, ok := ns.Annotations[stackStateAnnotation]; ok {
+		if err := json.Unmarshal([]byte(data), &state); err != nil {
+			return fmt.Errorf("invalid stack state on namespace %s: %w", nsArgs, err)
+		}
+	}
+
+	update(&state)
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	if ns.Annotations == nil {
+		ns.Annotations = map[string]string{}
+	}
+
+	ns.Annotations[stackStateAnnotation] = string(data)
+	_, err = config.CLIENTSET.CoreV1().Namespaces().Update(ctx, ns, metav1.UpdateOptions{})
+
+	return err
+}
@@ -2,27 +2,26 @@ package chain
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"strconv"
 	"time"
 
 	appsv1 "k8s.io/api/apps/v1"
 	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/labels"
-	"k8s.io/client-go/informers"
-	v1 "k8s.io/client-go/informers/core/v1"
-	"k8s.io/client-go/tools/cache"
+	"k8s.io/apimachinery/pkg/types"
 
 	"cli/cmd/config"
 
 	"k8s.io/apimachinery/pkg/util/intstr"
-	"k8s.io/apimachinery/pkg/util/wait"
 )
 
-func CreateStateFulSet(nsArgs string) (string, error) {
-	getParam, err := GetTotalNode(nsArgs)
+func CreateStateFulSet(ctx context.Context, nsArgs string) (string, error) {
+	ctx, cancel := stepContext(ctx)
+	defer cancel()
+
+	getParam, err := GetTotalNode(ctx, nsArgs)
 
 	if err != nil {
 		return "", err
@@ -30,247 +29,308 @@ func CreateStateFulSet(nsArgs string) (string, error) {
 
 	totalNode, err := strconv.Atoi(getParam)
 
-	stackId, err := getStakeIdInfo(nsArgs)
+	stackId, err := getStakeIdInfo(ctx, nsArgs)
+
+	if err != nil {
+		return "", err
+	}
 
+	requestBody, err := loadStackRequest(ctx, nsArgs)
 	if err != nil {
 		return "", err
 	}
 
 	for i := 1; i <= totalNode; i++ {
-		var replicas int32 = 1
-		var jobName string = fmt.Sprintf("validator-node-%v", i)
-
-		jobSpec := appsv1.StatefulSetSpec{
-			Replicas:    &replicas,
-			ServiceName: jobName,
-			Selector: &metav1.LabelSelector{
-				MatchLabels: map[string]string{
-					"app":       "polygon-edge-network",
-					"namespace": nsArgs,
-				},
+		err := applyStatefulSet(ctx, nsArgs, newStatefulSet(nsArgs, stackId, i, requestBody))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	if err := waitForValidatorPods(ctx, nsArgs, totalNode); err != nil {
+		return "", err
+	}
+
+	return "Statefulset is successfully configured 🕹️", nil
+}
+
+// waitForValidatorPods waits until totalNode validator pods of the stack are running
+func waitForValidatorPods(ctx context.Context, nsArgs string, totalNode int) error {
+	// the resync checks the volumes again, a volume which can not be provisioned keeps its pod pending forever
+	informer := newInformer(config.CLIENTSET.CoreV1().RESTClient(), "pods", nsArgs, &apiv1.Pod{}, 5*time.Second, byLabels(map[string]string{
+		"app":       "polygon-edge-network",
+		"namespace": nsArgs,
+	}))
+	started := time.Now()
+
+	err := waitFor(ctx, func() (bool, error) {
+		running := 0
+		for _, object := range informer.GetStore().List() {
+			pod := object.(*apiv1.Pod)
+
+			switch pod.Status.Phase {
+			case apiv1.PodRunning:
+				running++
+			case apiv1.PodFailed:
+				return false, fmt.Errorf("pod %s failed: %s", pod.Name, pod.Status.Message)
+			}
+		}
+
+		if running >= totalNode {
+			return true, nil
+		}
+
+		return false, storageError(ctx, nsArgs, started)
+	}, informer)
+	if err != nil {
+		return fmt.Errorf("%d validator pods are not running: %w", totalNode, err)
+	}
+
+	return nil
+}
+
+// replaceValidatorPods deletes the pods of the given validators and waits until their
+// statefulsets created new ones, a deleted pod may still be counted as ready for a while
+func replaceValidatorPods(ctx context.Context, nsArgs string, indexes []int) error {
+	pods := config.CLIENTSET.CoreV1().Pods(nsArgs)
+	previous := map[string]types.UID{}
+
+	for _, i := range indexes {
+		name := fmt.Sprintf("validator-node-%v-0", i)
+
+		pod, err := pods.Get(ctx, name, metav1.GetOptions{})
+		if err == nil {
+			previous[name] = pod.UID
+		} else if !errors.IsNotFound(err) {
+			return err
+		}
+
+		if err := pods.Delete(ctx, name, metav1.DeleteOptions{}); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	informer := newInformer(config.CLIENTSET.CoreV1().RESTClient(), "pods", nsArgs, &apiv1.Pod{}, 0, byLabels(map[string]string{
+		"app":       "polygon-edge-network",
+		"namespace": nsArgs,
+	}))
+
+	err := waitFor(ctx, func() (bool, error) {
+		for _, i := range indexes {
+			name := fmt.Sprintf("validator-node-%v-0", i)
+
+			object, exists, err := storedObject(informer, nsArgs, name)
+			if err != nil || !exists || object.(*apiv1.Pod).UID == previous[name] {
+				return false, err
+			}
+		}
+
+		return true, nil
+	}, informer)
+	if err != nil {
+		return fmt.Errorf("validator pods were not replaced: %w", err)
+	}
+
+	return nil
+}
+
+// validatorNodeLabel tells the validator pods apart, so the service of a validator selects only its pod
+const validatorNodeLabel = "validator-node"
+
+// validatorPodLabels are the labels of the pod of validator i. Statefulsets created before the
+// node label keep selecting every pod of the stack, their selector is immutable
+func validatorPodLabels(nsArgs string, i int) map[string]string {
+	return map[string]string{
+		"app":              "polygon-edge-network",
+		"namespace":        nsArgs,
+		validatorNodeLabel: fmt.Sprintf("validator-node%v", i),
+	}
+}
+
+func newStatefulSet(nsArgs string, stackId string, i int, requestBody ConfigRequest) *appsv1.StatefulSet {
+	var replicas int32 = 1
+	var jobName string = fmt.Sprintf("validator-node-%v", i)
+
+	backend := getSecretsBackend(requestBody)
+	secretsVolumes, secretsMounts := backend.nodeVolumes(i)
+	_, tools := backend.tools()
+
+	jobSpec := appsv1.StatefulSetSpec{
+		Replicas:    &replicas,
+		ServiceName: fmt.Sprintf("validator-node%v-svc", i),
+		Selector: &metav1.LabelSelector{
+			MatchLabels: validatorPodLabels(nsArgs, i),
+		},
+		Template: apiv1.PodTemplateSpec{
+			ObjectMeta: metav1.ObjectMeta{
+				Labels: validatorPodLabels(nsArgs, i),
 			},
-			Template: apiv1.PodTemplateSpec{
-				ObjectMeta: metav1.ObjectMeta{
-					Labels: map[string]string{
-						"app":       "polygon-edge-network",
-						"namespace": nsArgs,
-					},
-				},
-				Spec: apiv1.PodSpec{
-					InitContainers: []apiv1.Container{
-						{
-							Name:  "fetch-from-vault",
-							Image: "alpine:latest",
-							Env: []apiv1.EnvVar{
-								{
-									Name:  "STACK_ID",
-									Value: stackId,
-								},
-								{
-									Name:  "VAULT_ADDR",
-									Value: config.VaultUrl,
-								},
-								{
-									Name:  "VAULT_TOKEN",
-									Value: config.VaultToken,
-								},
+			Spec: apiv1.PodSpec{
+				ServiceAccountName: backend.serviceAccount(requestBody),
+				ImagePullSecrets:   requestBody.Images.pullSecrets(),
+				InitContainers: []apiv1.Container{
+					{
+						Name:  "fetch-secrets",
+						Image: requestBody.Images.image(requestBody.Images.Fetch),
+						Env: append([]apiv1.EnvVar{
+							{
+								Name:  "STACK_ID",
+								Value: stackId,
 							},
-							Command: []string{"sh", "-c"},
-							VolumeMounts: []apiv1.VolumeMount{
-								{
-									Name:      fmt.Sprintf("data-validator-node%v", i),
-									MountPath: "/data",
-								},
-								{
-									Name:      "config-json",
-									MountPath: "/config",
-								},
+						}, backend.envs(nsArgs, requestBody)...),
+						Command: []string{"sh", "-c"},
+						VolumeMounts: append([]apiv1.VolumeMount{
+							{
+								Name:      fmt.Sprintf("data-validator-node%v", i),
+								MountPath: "/data",
 							},
-							Args: []string{
-								fmt.Sprintf(
-									`         
-									#!/usr/bin/env sh
-									# Install jq and curl
-									apk add --no-cache jq
-									apk add curl
-									# Set vault variables
-									set -e
-									
-									SECRET_PATH="polygon-edge/data/${STACK_ID}/genesis.json"
-									JSON_FILE_PATH="/data/genesis.json"
-						
-									curl --header "X-Vault-Token: ${VAULT_TOKEN}" \
-									${VAULT_ADDR}/v1/${SECRET_PATH} | jq -r '.data.data' > ${JSON_FILE_PATH}
-						
-									ls -lrt /data
-						
-									cat /data/genesis.json
-						
-									VAULTCONFIG_SECRET_PATH="polygon-edge/data/${STACK_ID}/node%v/vaultsecretsconfig.json"
-									VAULTCONFIG_JSON_FILE_PATH="/data/vaultsecretsconfig.json"
-									curl --header "X-Vault-Token: ${VAULT_TOKEN}" \
-									${VAULT_ADDR}/v1/${VAULTCONFIG_SECRET_PATH} | jq -r '.data.data' > ${VAULTCONFIG_JSON_FILE_PATH}
-								  `, i),
+							{
+								Name:      "config-json",
+								MountPath: "/config",
 							},
+						}, secretsMounts...),
+						Args: []string{
+							fmt.Sprintf(
+								`         
+								#!/usr/bin/env sh
+								# Make sure the tools of the script are installed
+								%s
+								# Fetch the genesis and the validator secrets
+								set -e
+								%s
+							  `, requestBody.Images.installScript(tools), backend.fetchScript(i)),
 						},
 					},
-					Volumes: []apiv1.Volume{
-						{
-							Name: fmt.Sprintf("data-validator-node%v", i),
-							VolumeSource: apiv1.VolumeSource{
-								PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{
-									ClaimName: fmt.Sprintf("polygon-edge-validator-%v-pvc", i),
-								},
+				},
+				Volumes: append([]apiv1.Volume{
+					{
+						Name: fmt.Sprintf("data-validator-node%v", i),
+						VolumeSource: apiv1.VolumeSource{
+							PersistentVolumeClaim: &apiv1.PersistentVolumeClaimVolumeSource{
+								ClaimName: requestBody.validatorClaim(i),
 							},
 						},
-						{
-							Name: "config-json",
-							VolumeSource: apiv1.VolumeSource{
-								ConfigMap: &apiv1.ConfigMapVolumeSource{
-									LocalObjectReference: apiv1.LocalObjectReference{
-										Name: fmt.Sprintf("validator-node%v-config", i),
-									},
+					},
+					{
+						Name: "config-json",
+						VolumeSource: apiv1.VolumeSource{
+							ConfigMap: &apiv1.ConfigMapVolumeSource{
+								LocalObjectReference: apiv1.LocalObjectReference{
+									Name: fmt.Sprintf("validator-node%v-config", i),
 								},
 							},
 						},
 					},
-					Containers: []apiv1.Container{
-						{
-							Name:    jobName,
-							Image:   "0xpolygon/polygon-edge:0.9.0",
-							Command: []string{"sh", "-c"},
-							Args: []string{
-								fmt.Sprintf(`         
-									echo "Executing"
-									polygon-edge server --config /config/node%vconfig.json
-								  `, i),
+				}, secretsVolumes...),
+				Containers: []apiv1.Container{
+					{
+						Name:      jobName,
+						Image:     requestBody.Images.image(requestBody.Image),
+						Resources: requestBody.Resources.requirements(),
+						Command:   []string{"sh", "-c"},
+						Args: []string{
+							fmt.Sprintf(`         
+								echo "Executing"
+								polygon-edge server --config /config/node%vconfig.json
+							  `, i),
+						},
+						Ports: []apiv1.ContainerPort{
+							{
+								Name:          "grpc",
+								ContainerPort: 9632,
 							},
-							Ports: []apiv1.ContainerPort{
-								{
-									Name:          "grpc",
-									ContainerPort: 9632,
-								},
-								{
-									Name:          "jsonrpc",
-									ContainerPort: 8545,
-								},
-								{
-									Name:          "prometheus",
-									ContainerPort: 5001,
-								},
-								{
-									Name:          "libp2p",
-									ContainerPort: 1478,
-								},
+							{
+								Name:          "jsonrpc",
+								ContainerPort: 8545,
 							},
-							VolumeMounts: []apiv1.VolumeMount{
-								{
-									Name:      fmt.Sprintf("data-validator-node%v", i),
-									MountPath: "/data",
-								},
-								{
-									Name:      "config-json",
-									MountPath: "/config",
-								},
+							{
+								Name:          "prometheus",
+								ContainerPort: 5001,
+							},
+							{
+								Name:          "libp2p",
+								ContainerPort: 1478,
+							},
+						},
+						VolumeMounts: []apiv1.VolumeMount{
+							{
+								Name:      fmt.Sprintf("data-validator-node%v", i),
+								MountPath: "/data",
+							},
+							{
+								Name:      "config-json",
+								MountPath: "/config",
 							},
 						},
 					},
 				},
 			},
-		}
+		},
+	}
 
-		// Make ConfigMap
-		sts := &appsv1.StatefulSet{
-			TypeMeta: metav1.TypeMeta{
-				Kind:       "StatefulSet",
-				APIVersion: "apps/v1",
-			},
-			ObjectMeta: metav1.ObjectMeta{
-				Name:      jobName,
-				Namespace: nsArgs,
-				Labels: map[string]string{
-					"app":       "polygon-edge-network",
-					"namespace": nsArgs,
-				},
+	// Make ConfigMap
+	sts := &appsv1.StatefulSet{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "StatefulSet",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName,
+			Namespace: nsArgs,
+			Labels: map[string]string{
+				"app":       "polygon-edge-network",
+				"namespace": nsArgs,
 			},
-			Spec: jobSpec,
-		}
-		_, err := config.CLIENTSET.AppsV1().StatefulSets(nsArgs).Create(context.TODO(), sts, metav1.CreateOptions{})
-		if err != nil {
-			return "", err
-		}
+		},
+		Spec: jobSpec,
 	}
 
-	pi := createPodInformer()
-	labelMap, err := metav1.LabelSelectorAsMap(&metav1.LabelSelector{
-		MatchLabels: map[string]string{
-			"app":       "polygon-edge-network",
-			"namespace": nsArgs,
-		},
-	})
+	return sts
+}
+
+func CreateLoadBalancer(ctx context.Context, nsArgs string) (string, error) {
+	requestBody, err := loadStackRequest(ctx, nsArgs)
 	if err != nil {
 		return "", err
 	}
 
-	var podsStatusCount int
-	var podsStatus = map[string]bool{}
+	err = applyService(ctx, nsArgs, newLoadBalancer(nsArgs, requestBody))
+	if err != nil {
+		return "", err
+	}
 
-	for {
-		pods, err := pi.Lister().List(labels.SelectorFromSet(labelMap))
-		if err != nil {
-			return "", err
-		}
+	// only LoadBalancer services get an ingress address to wait for
+	if requestBody.ServiceType != string(apiv1.ServiceTypeLoadBalancer) {
+		return fmt.Sprintf("%s service is successfully configured 📦", requestBody.ServiceType), nil
+	}
 
-		if len(pods) == 0 {
-			continue
-		}
+	ctx, cancel := stepContext(ctx)
+	defer cancel()
 
-		for _, value := range pods {
-			if value.Status.Phase == "Running" {
-				if !podsStatus[value.Name] {
-					podsStatus[value.Name] = true
-					podsStatusCount++
-				}
-			}
+	informer := newInformer(config.CLIENTSET.CoreV1().RESTClient(), "services", nsArgs, &apiv1.Service{}, 0, byName("polygon-edge-svc"))
 
-			if value.Status.Phase == "Failed" {
-				return "", err
-			}
+	err = waitFor(ctx, func() (bool, error) {
+		object, exists, err := storedObject(informer, nsArgs, "polygon-edge-svc")
+		if err != nil || !exists {
+			return false, err
 		}
 
-		if totalNode == podsStatusCount {
-			break
+		for _, ingress := range object.(*apiv1.Service).Status.LoadBalancer.Ingress {
+			if ingress.IP != "" || ingress.Hostname != "" {
+				return true, nil
+			}
 		}
 
-		time.Sleep(1 * time.Second)
+		return false, nil
+	}, informer)
+	if err != nil {
+		return "", fmt.Errorf("LoadBalancer got no address, the cluster may have no LoadBalancer controller: %w", err)
 	}
 
-	return "Statefulset is successfully configured 🕹️", nil
-}
-
-func createPodInformer() v1.PodInformer {
-	informerFactory := informers.NewSharedInformerFactory(config.CLIENTSET, time.Second*30)
-	podInfoermer := informerFactory.Core().V1().Pods()
-	podInfoermer.Informer().AddEventHandler(
-		cache.ResourceEventHandlerFuncs{
-			// AddFunc: func(new interface{}) {
-			// 	log.Println(new)
-			// },
-			UpdateFunc: func(old, new interface{}) {
-				_ = new.(*apiv1.Pod)
-				// log.Println(pod.Status.Phase)
-			},
-			// DeleteFunc: func(obj interface{}) {
-			// 	log.Println(obj)
-			// },
-		},
-	)
-	informerFactory.Start(wait.NeverStop)
-	informerFactory.WaitForCacheSync(wait.NeverStop)
-	return podInfoermer
+	return "LoadBalancer is successfully configured 📦", nil
 }
 
-func CreateLoadBalancer(nsArgs string) (string, error) {
+func newLoadBalancer(nsArgs string, requestBody ConfigRequest) *apiv1.Service {
 	servicePVC := &apiv1.Service{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Service",
@@ -281,7 +341,7 @@ func CreateLoadBalancer(nsArgs string) (string, error) {
 			Namespace: nsArgs,
 		},
 		Spec: apiv1.ServiceSpec{
-			Type: apiv1.ServiceTypeLoadBalancer,
+			Type: apiv1.ServiceType(requestBody.ServiceType),
 			Selector: map[string]string{
 				"app":       "polygon-edge-network",
 				"namespace": nsArgs,
@@ -322,45 +382,23 @@ func CreateLoadBalancer(nsArgs string) (string, error) {
 			},
 		},
 	}
-	_, err := config.CLIENTSET.CoreV1().Services(nsArgs).Create(context.TODO(), servicePVC, metav1.CreateOptions{})
-	if err != nil {
-		return "", err
-	}
-
-	var ip_addr string
-	for {
-		res, err := config.CLIENTSET.CoreV1().Services(nsArgs).Get(context.TODO(), "polygon-edge-svc", metav1.GetOptions{})
-
-		if err != nil {
-			return "", err
-		}
-
-		if len(res.Status.LoadBalancer.Ingress) > 0 {
-			ip_addr = res.Status.LoadBalancer.Ingress[0].IP
-			break
-		}
-		time.Sleep(1 * time.Second)
-	}
-
-	if ip_addr != "" {
-		return "LoadBalancer is successfully configured 📦", nil
-	} else {
-		return ip_addr, errors.New("LoadBalancer config is failed")
-	}
+	return servicePVC
 }
 
-func GetLoadBalancerInfo(nsArgs string) (string, error) {
-	res, err := config.CLIENTSET.CoreV1().Services(nsArgs).Get(context.TODO(), "polygon-edge-svc", metav1.GetOptions{})
+func GetLoadBalancerInfo(ctx context.Context, nsArgs string) (string, error) {
+	res, err := config.CLIENTSET.CoreV1().Services(nsArgs).Get(ctx, "polygon-edge-svc", metav1.GetOptions{})
 	if err != nil {
 		return "", err
 	}
 
-	var ip_addr string
+	// load balancers of some clouds, like aws, get a hostname instead of an ip
+	var address string
 	if len(res.Status.LoadBalancer.Ingress) > 0 {
-		ip_addr = res.Status.LoadBalancer.Ingress[0].IP
+		address = res.Status.LoadBalancer.Ingress[0].IP
+		if address == "" {
+			address = res.Status.LoadBalancer.Ingress[0].Hostname
+		}
 	}
 
-	_ = ip_addr
-
-	return ip_addr, nil
+	return address, nil
 }
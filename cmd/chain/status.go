@@ -0,0 +1,148 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+
+	apiv1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"cli/cmd/config"
+)
+
+const (
+	HealthHealthy  = "healthy"
+	HealthDegraded = "degraded"
+	HealthPending  = "pending"
+
+	statusMissing = "Missing"
+)
+
+type NodeStatus struct {
+	Name      string
+	Ready     bool
+	PodPhase  string
+	PVCPhase  string
+	ServiceIP string
+}
+
+type StackStatus struct {
+	StakeId        string
+	TotalNode      string
+	JobStatus      string
+	PremineFund    string
+	LoadBalancerIP string
+	Health         string
+	Nodes          []NodeStatus
+}
+
+// GetStackStatus collects the state of every object genesis creates for the stack
+func GetStackStatus(ctx context.Context, nsArgs string) (*StackStatus, error) {
+	getParam, err := GetTotalNode(ctx, nsArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &StackStatus{
+		StakeId:   nsArgs,
+		TotalNode: getParam,
+		JobStatus: getJobStatus(ctx, nsArgs),
+	}
+
+	if details, err := GetJobDetails(ctx, nsArgs); err == nil {
+		status.StakeId = fmt.Sprint(details["STACK_ID"])
+		status.PremineFund = fmt.Sprint(details["PREMINE_FUND"])
+	} else {
+		status.PremineFund = requestBody.NodePremineAmount
+	}
+
+	if ip, err := GetLoadBalancerInfo(ctx, nsArgs); err == nil {
+		status.LoadBalancerIP = ip
+	}
+
+	indexes, err := ValidatorIndexes(ctx, nsArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	// only a LoadBalancer service gets an external address
+	healthy := status.JobStatus == "Succeeded"
+	if requestBody.ServiceType == string(apiv1.ServiceTypeLoadBalancer) && status.LoadBalancerIP == "" {
+		healthy = false
+	}
+
+	for _, i := range indexes {
+		node := getNodeStatus(ctx, nsArgs, i, requestBody)
+		if !node.Ready || node.PVCPhase != "Bound" || node.ServiceIP == "" {
+			healthy = false
+		}
+
+		status.Nodes = append(status.Nodes, node)
+	}
+
+	switch {
+	case healthy:
+		status.Health = HealthHealthy
+	case status.JobStatus == "Running":
+		status.Health = HealthPending
+	default:
+		status.Health = HealthDegraded
+	}
+
+	return status, nil
+}
+
+func getJobStatus(ctx context.Context, nsArgs string) string {
+	job, err := config.CLIENTSET.BatchV1().Jobs(nsArgs).Get(ctx, "polygon-edge-job", metav1.GetOptions{})
+	if err != nil {
+		// a genesis generated by the cli has no helper job, storing it is what the job would have done
+		if hasLocalGenesis(ctx, nsArgs) {
+			return "Succeeded"
+		}
+
+		return statusMissing
+	}
+
+	if job.Status.Succeeded > 0 {
+		return "Succeeded"
+	} else if job.Status.Failed > 0 {
+		return "Failed"
+	}
+
+	return "Running"
+}
+
+func getNodeStatus(ctx context.Context, nsArgs string, i int, requestBody ConfigRequest) NodeStatus {
+	node := NodeStatus{
+		Name:     fmt.Sprintf("validator-node-%v", i),
+		PodPhase: statusMissing,
+		PVCPhase: statusMissing,
+	}
+
+	sts, err := config.CLIENTSET.AppsV1().StatefulSets(nsArgs).Get(ctx, node.Name, metav1.GetOptions{})
+	if err == nil {
+		node.Ready = sts.Status.ReadyReplicas > 0
+	}
+
+	pod, err := config.CLIENTSET.CoreV1().Pods(nsArgs).Get(ctx, fmt.Sprintf("%s-0", node.Name), metav1.GetOptions{})
+	if err == nil {
+		node.PodPhase = string(pod.Status.Phase)
+	}
+
+	pvc, err := config.CLIENTSET.CoreV1().PersistentVolumeClaims(nsArgs).Get(ctx, requestBody.validatorClaim(i), metav1.GetOptions{})
+	if err == nil {
+		node.PVCPhase = string(pvc.Status.Phase)
+	}
+
+	svc, err := config.CLIENTSET.CoreV1().Services(nsArgs).Get(ctx, fmt.Sprintf("validator-node%v-svc", i), metav1.GetOptions{})
+	if err == nil {
+		node.ServiceIP = svc.Spec.ClusterIP
+	}
+
+	return node
+}
@@ -0,0 +1,365 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	apiv1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+
+	"cli/cmd/config"
+)
+
+// resizeRestartAfter is how long a pending file system resize may take before the pod is restarted
+const resizeRestartAfter = time.Minute
+
+const (
+	// provisionFailures is how often a volume may fail to be provisioned before the wait gives up
+	provisionFailures = 3
+	// provisionGracePeriod is how long a volume may stay pending after a failed provisioning
+	provisionGracePeriod = 2 * time.Minute
+)
+
+const (
+	StoragePresetGCE       = "gce"
+	StoragePresetEBS       = "ebs"
+	StoragePresetAzureDisk = "azure-disk"
+	StoragePresetLocalPath = "local-path"
+)
+
+// storagePreset is the provisioner the polygonsc storage class is created with
+type storagePreset struct {
+	provisioner string
+	parameters  map[string]string
+	// expansion is whether the provisioner can grow a bound volume
+	expansion bool
+	// inTree is the provisioner the csi driver replaced, kubernetes migrates its classes to the driver
+	inTree string
+}
+
+var storagePresets = map[string]storagePreset{
+	StoragePresetGCE: {
+		provisioner: "pd.csi.storage.gke.io",
+		parameters:  map[string]string{"type": "pd-standard"},
+		expansion:   true,
+		inTree:      "kubernetes.io/gce-pd",
+	},
+	StoragePresetEBS: {
+		provisioner: "ebs.csi.aws.com",
+		parameters:  map[string]string{"type": "gp3"},
+		expansion:   true,
+	},
+	StoragePresetAzureDisk: {
+		provisioner: "disk.csi.azure.com",
+		parameters:  map[string]string{"skuname": "StandardSSD_LRS"},
+		expansion:   true,
+	},
+	StoragePresetLocalPath: {
+		provisioner: "rancher.io/local-path",
+	},
+}
+
+// StoragePresets returns the names of the storage class presets
+func StoragePresets() []string {
+	var presets []string
+	for preset := range storagePresets {
+		presets = append(presets, preset)
+	}
+
+	sort.Strings(presets)
+
+	return presets
+}
+
+// ensureStorageClass creates the polygonsc class from the preset of the request, or makes sure
+// the class the request reuses exists
+func ensureStorageClass(ctx context.Context, requestBody ConfigRequest) error {
+	classes := config.CLIENTSET.StorageV1().StorageClasses()
+
+	if requestBody.Storage.ClassName != storageClass {
+		if _, err := classes.Get(ctx, requestBody.Storage.ClassName, metav1.GetOptions{}); err != nil {
+			return fmt.Errorf("storage class %s can not be used: %w", requestBody.Storage.ClassName, err)
+		}
+
+		return nil
+	}
+
+	class := newStorageClass(requestBody)
+
+	_, err := classes.Create(ctx, class, metav1.CreateOptions{})
+	if !errors.IsAlreadyExists(err) {
+		return err
+	}
+
+	// the class is shared by every stack of the cluster, a stack of another preset can not reuse it
+	existing, err := classes.Get(ctx, storageClass, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	// a class created before the preset moved to the csi driver is still served by it
+	inTree := storagePresets[requestBody.Storage.Preset].inTree
+	if existing.Provisioner != class.Provisioner && (inTree == "" || existing.Provisioner != inTree) {
+		return fmt.Errorf("storage class %s already exists with provisioner %s, not %s of the %s preset",
+			storageClass, existing.Provisioner, class.Provisioner, requestBody.Storage.Preset)
+	}
+
+	return nil
+}
+
+// storageError returns the latest provisioning failure of a volume of the stack since the given
+// time. Provisioners report transient failures as well, a failure only counts once it repeated or
+// the claim is still pending after provisionGracePeriod
+func storageError(ctx context.Context, nsArgs string, since time.Time) error {
+	events, err := config.CLIENTSET.CoreV1().Events(nsArgs).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(fields.Set{
+			"involvedObject.kind": "PersistentVolumeClaim",
+			"reason":              "ProvisioningFailed",
+		}).String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	type failure struct {
+		count  int32
+		first  time.Time
+		latest *apiv1.Event
+	}
+
+	failures := map[string]*failure{}
+	for i, event := range events.Items {
+		if event.LastTimestamp.Time.Before(since) {
+			continue
+		}
+
+		// a repeated event is counted on the same object, its first occurrence may predate the wait
+		first := event.FirstTimestamp.Time
+		if first.Before(since) {
+			first = since
+		}
+
+		count := event.Count
+		if count < 1 {
+			count = 1
+		}
+
+		name := event.InvolvedObject.Name
+		if failures[name] == nil {
+			failures[name] = &failure{first: first}
+		}
+
+		f := failures[name]
+		f.count += count
+		if first.Before(f.first) {
+			f.first = first
+		}
+		if f.latest == nil || event.LastTimestamp.After(f.latest.LastTimestamp.Time) {
+			f.latest = &events.Items[i]
+		}
+	}
+
+	for name, f := range failures {
+		if f.count < provisionFailures && time.Since(f.first) < provisionGracePeriod {
+			continue
+		}
+
+		// the failure only matters while the claim still waits for its volume
+		pvc, err := config.CLIENTSET.CoreV1().PersistentVolumeClaims(nsArgs).Get(ctx, name, metav1.GetOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if pvc.Status.Phase == apiv1.ClaimPending {
+			return fmt.Errorf("volume %s can not be provisioned: %s", name, f.latest.Message)
+		}
+	}
+
+	return nil
+}
+
+func newStorageClass(requestBody ConfigRequest) *storagev1.StorageClass {
+	preset := storagePresets[requestBody.Storage.Preset]
+
+	storage := &storagev1.StorageClass{
+		AllowVolumeExpansion: toGetBooleanPtr(preset.expansion),
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "StorageClass",
+			APIVersion: "storage.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: storageClass,
+		},
+		Parameters:        preset.parameters,
+		Provisioner:       preset.provisioner,
+		ReclaimPolicy:     toPVReclaimPolicyPtr("Delete"),
+		VolumeBindingMode: toModePtr(storagev1.VolumeBindingWaitForFirstConsumer),
+	}
+
+	return storage
+}
+
+// ResizeValidatorVolume grows the data volume of validator i to size and waits until the volume
+// and its file system have the new size. A pod whose file system is only resized on mount is restarted
+func ResizeValidatorVolume(ctx context.Context, nsArgs string, i int, size resource.Quantity) (string, error) {
+	ctx, cancel := stepContext(ctx)
+	defer cancel()
+
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+
+	claims := config.CLIENTSET.CoreV1().PersistentVolumeClaims(nsArgs)
+	name := requestBody.validatorClaim(i)
+
+	pvc, err := claims.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	current := pvc.Spec.Resources.Requests[apiv1.ResourceStorage]
+	switch current.Cmp(size) {
+	case 1:
+		return "", fmt.Errorf("volume %s has %s, volumes can not shrink", name, current.String())
+	case 0:
+		if capacity := pvc.Status.Capacity[apiv1.ResourceStorage]; capacity.Cmp(size) >= 0 {
+			return fmt.Sprintf("Validator %d volume already has %s 💾", i, size.String()), nil
+		}
+	default:
+		if err := checkVolumeExpansion(ctx, pvc); err != nil {
+			return "", err
+		}
+
+		pvc.Spec.Resources.Requests[apiv1.ResourceStorage] = size
+		if _, err := claims.Update(ctx, pvc, metav1.UpdateOptions{}); err != nil {
+			return "", err
+		}
+	}
+
+	restarted, err := waitForVolumeResize(ctx, nsArgs, i, name, size)
+	if err != nil {
+		return "", err
+	}
+
+	if restarted {
+		return fmt.Sprintf("Validator %d volume is successfully resized to %s, its pod was restarted 💾", i, size.String()), nil
+	}
+
+	return fmt.Sprintf("Validator %d volume is successfully resized to %s 💾", i, size.String()), nil
+}
+
+// SetStorageSize records the volume size of the stack, so validators added later get it as well.
+// A stack created before the request was stored gets its defaults stored
+func SetStorageSize(ctx context.Context, nsArgs string, size resource.Quantity) error {
+	return updateStackRequest(ctx, nsArgs, func(requestBody *ConfigRequest) {
+		requestBody.Storage.Size = size.String()
+	})
+}
+
+func checkVolumeExpansion(ctx context.Context, pvc *apiv1.PersistentVolumeClaim) error {
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+		return fmt.Errorf("volume %s has no storage class and can not be expanded", pvc.Name)
+	}
+
+	class, err := config.CLIENTSET.StorageV1().StorageClasses().Get(ctx, *pvc.Spec.StorageClassName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	if class.AllowVolumeExpansion == nil || !*class.AllowVolumeExpansion {
+		return fmt.Errorf("storage class %s of volume %s does not allow volume expansion", class.Name, pvc.Name)
+	}
+
+	return nil
+}
+
+// waitForVolumeResize waits until the capacity of the volume of validator i reaches size. When
+// the file system resize stays pending the driver only resizes on mount, so the pod is restarted
+func waitForVolumeResize(ctx context.Context, nsArgs string, i int, name string, size resource.Quantity) (bool, error) {
+	// the resync checks the events and the pending resize again while the claim does not change
+	informer := newInformer(config.CLIENTSET.CoreV1().RESTClient(), "persistentvolumeclaims", nsArgs, &apiv1.PersistentVolumeClaim{}, 5*time.Second, byName(name))
+	started := time.Now()
+
+	var pending time.Time
+	restarted := false
+
+	err := waitFor(ctx, func() (bool, error) {
+		object, exists, err := storedObject(informer, nsArgs, name)
+		if err != nil {
+			return false, err
+		} else if !exists {
+			return false, fmt.Errorf("volume %s is gone", name)
+		}
+
+		pvc := object.(*apiv1.PersistentVolumeClaim)
+		if capacity := pvc.Status.Capacity[apiv1.ResourceStorage]; capacity.Cmp(size) >= 0 {
+			return true, nil
+		}
+
+		for _, condition := range pvc.Status.Conditions {
+			if condition.Type == apiv1.PersistentVolumeClaimFileSystemResizePending && condition.Status == apiv1.ConditionTrue && pending.IsZero() {
+				pending = time.Now()
+			}
+		}
+
+		// the controller and the kubelet report failed resizes as events of the claim
+		if err := resizeError(ctx, nsArgs, name, started); err != nil {
+			return false, err
+		}
+
+		if !restarted && !pending.IsZero() && time.Since(pending) > resizeRestartAfter {
+			if err := restartValidatorPod(ctx, nsArgs, i); err != nil {
+				return false, err
+			}
+
+			restarted = true
+		}
+
+		return false, nil
+	}, informer)
+	if err != nil {
+		return restarted, fmt.Errorf("volume %s was not resized to %s: %w", name, size.String(), err)
+	}
+
+	return restarted, nil
+}
+
+// resizeError returns the latest resize failure of the volume since the given time
+func resizeError(ctx context.Context, nsArgs string, name string, since time.Time) error {
+	events, err := config.CLIENTSET.CoreV1().Events(nsArgs).List(ctx, metav1.ListOptions{
+		FieldSelector: fields.SelectorFromSet(fields.Set{
+			"involvedObject.kind": "PersistentVolumeClaim",
+			"involvedObject.name": name,
+			"reason":              "VolumeResizeFailed",
+		}).String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events.Items {
+		if !event.LastTimestamp.Time.Before(since) {
+			return fmt.Errorf("volume %s can not be resized: %s", name, event.Message)
+		}
+	}
+
+	return nil
+}
+
+// restartValidatorPod deletes the pod of validator i and waits until its statefulset runs it again
+func restartValidatorPod(ctx context.Context, nsArgs string, i int) error {
+	if err := replaceValidatorPods(ctx, nsArgs, []int{i}); err != nil {
+		return err
+	}
+
+	return waitForStatefulSetRollout(ctx, nsArgs, fmt.Sprintf("validator-node-%v", i))
+}
@@ -0,0 +1,189 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"cli/cmd/config"
+)
+
+const (
+	// upgradeBlockTimeout is how long the chain may go without a new block after a validator was upgraded
+	upgradeBlockTimeout = 2 * time.Minute
+)
+
+type blockNumberResponse struct {
+	Result string `json:"result"`
+	Error  *struct {
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// UpgradeImage resolves the image of an upgrade, a bare tag keeps the repository of the stack image
+func UpgradeImage(ctx context.Context, nsArgs string, image string) (string, error) {
+	if strings.ContainsAny(image, ":/@") {
+		return image, nil
+	}
+
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+
+	repository := requestBody.Image
+	if index := strings.LastIndex(repository, ":"); index > strings.LastIndex(repository, "/") {
+		repository = repository[:index]
+	}
+
+	return repository + ":" + image, nil
+}
+
+// UpgradeValidator moves validator i to image, waits until its pod is ready and until it sees
+// the chain producing blocks again
+func UpgradeValidator(ctx context.Context, nsArgs string, i int, image string) (string, error) {
+	ctx, cancel := stepContext(ctx)
+	defer cancel()
+
+	name := fmt.Sprintf("validator-node-%v", i)
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+	image = requestBody.Images.image(image)
+
+	statefulSet, err := config.CLIENTSET.AppsV1().StatefulSets(nsArgs).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	container := &statefulSet.Spec.Template.Spec.Containers[0]
+	if container.Image == image {
+		return fmt.Sprintf("Validator %d already runs %s ⏭️", i, image), nil
+	}
+
+	// the height before the restart, a node which can not report it is measured from its own restart
+	before, _ := blockNumber(ctx, nsArgs, i)
+
+	container.Image = image
+	if _, err := config.CLIENTSET.AppsV1().StatefulSets(nsArgs).Update(ctx, statefulSet, metav1.UpdateOptions{}); err != nil {
+		return "", err
+	}
+
+	if err := waitForStatefulSetRollout(ctx, nsArgs, name); err != nil {
+		return "", fmt.Errorf("validator %d did not become ready on %s: %w", i, image, err)
+	}
+
+	height, err := waitForBlockProduction(ctx, nsArgs, i, before)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Validator %d is successfully upgraded to %s at block %d 🚀", i, image, height), nil
+}
+
+// SetStackImage records the image of the stack once every validator runs it, so validators added
+// later run it as well. The helper image follows when it was the node image. A stack created
+// before the request was stored gets its defaults stored
+func SetStackImage(ctx context.Context, nsArgs string, image string) error {
+	return updateStackRequest(ctx, nsArgs, func(requestBody *ConfigRequest) {
+		if requestBody.Images.Helper != "" && requestBody.Images.Helper == requestBody.WithDefaults().Image {
+			requestBody.Images.Helper = image
+		}
+
+		requestBody.Image = image
+	})
+}
+
+func waitForStatefulSetRollout(ctx context.Context, nsArgs string, name string) error {
+	informer := newInformer(config.CLIENTSET.AppsV1().RESTClient(), "statefulsets", nsArgs, &appsv1.StatefulSet{}, 0, byName(name))
+
+	err := waitFor(ctx, func() (bool, error) {
+		object, exists, err := storedObject(informer, nsArgs, name)
+		if err != nil {
+			return false, err
+		} else if !exists {
+			return false, fmt.Errorf("statefulset %s is gone", name)
+		}
+
+		return isRolledOut(object.(*appsv1.StatefulSet)), nil
+	}, informer)
+	if err != nil {
+		return fmt.Errorf("statefulset %s was not rolled out: %w", name, err)
+	}
+
+	return nil
+}
+
+func isRolledOut(statefulSet *appsv1.StatefulSet) bool {
+	status := statefulSet.Status
+
+	return status.ObservedGeneration >= statefulSet.Generation &&
+		status.UpdateRevision == status.CurrentRevision &&
+		status.UpdatedReplicas == *statefulSet.Spec.Replicas &&
+		status.ReadyReplicas == *statefulSet.Spec.Replicas
+}
+
+// waitForBlockProduction waits until validator i reports a block above before and returns its height
+func waitForBlockProduction(ctx context.Context, nsArgs string, i int, before uint64) (uint64, error) {
+	stallCtx, cancel := context.WithTimeout(ctx, upgradeBlockTimeout)
+	defer cancel()
+
+	var height uint64
+	var rpcErr error
+
+	err := pollFor(stallCtx, 3*time.Second, func() (bool, error) {
+		height, rpcErr = blockNumber(ctx, nsArgs, i)
+
+		return rpcErr == nil && height > before, nil
+	})
+
+	// the step itself ran out of time or was interrupted
+	if err != nil && ctx.Err() != nil {
+		return 0, waitError(ctx)
+	}
+
+	if err == nil {
+		return height, nil
+	}
+
+	if rpcErr != nil {
+		return 0, fmt.Errorf("validator %d does not answer on json-rpc after its upgrade: %w", i, rpcErr)
+	}
+
+	return 0, fmt.Errorf("the chain stalled at block %d after validator %d was upgraded, no block within %s", height, i, upgradeBlockTimeout)
+}
+
+// blockNumber asks validator i for its latest block through the pod proxy of the api server
+func blockNumber(ctx context.Context, nsArgs string, i int) (uint64, error) {
+	request := []byte(`{"jsonrpc":"2.0","method":"eth_blockNumber","params":[],"id":1}`)
+
+	data, err := config.CLIENTSET.CoreV1().RESTClient().Post().
+		Namespace(nsArgs).
+		Resource("pods").
+		Name(fmt.Sprintf("validator-node-%v-0:8545", i)).
+		SubResource("proxy").
+		SetHeader("Content-Type", "application/json").
+		Body(request).
+		DoRaw(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var response blockNumberResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return 0, err
+	}
+
+	if response.Error != nil {
+		return 0, fmt.Errorf("eth_blockNumber: %s", response.Error.Message)
+	}
+
+	return hexutil.DecodeUint64(response.Result)
+}
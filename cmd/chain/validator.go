@@ -0,0 +1,725 @@
+package chain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apiv1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"cli/cmd/config"
+)
+
+const (
+	// validatorSecretsJob generates the secrets of validators added to a running stack
+	validatorSecretsJob = "polygon-edge-validator-secrets"
+
+	// validatorProposalJob submits the ibft votes adding or removing validators
+	validatorProposalJob = "polygon-edge-validator-proposal"
+
+	// validatorKeysPrefix marks the lines of the secrets job log carrying the public keys of a validator
+	validatorKeysPrefix = "VALIDATOR"
+
+	// nodeIndexesAnnotation lists the validator indexes of a stack once validators were added or removed
+	nodeIndexesAnnotation = "polygon-supernet-cli/nodes"
+
+	// validatorVoteTimeout is how long the proposal job waits for the votes to be sealed, in seconds
+	validatorVoteTimeout = 600
+
+	VoteAuth = "auth"
+	VoteDrop = "drop"
+)
+
+// ValidatorKeys are the public keys of a validator
+type ValidatorKeys struct {
+	Index     int    `json:"index"`
+	Address   string `json:"address"`
+	BLSPubkey string `json:"blsPubkey,omitempty"`
+	NodeID    string `json:"nodeId,omitempty"`
+}
+
+// CheckValidatorChange makes sure the validator set of the stack can be changed by ibft votes
+func CheckValidatorChange(ctx context.Context, nsArgs string) error {
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if err != nil {
+		return err
+	}
+
+	// polybft validators join and leave through the stake manager of the rootchain
+	if requestBody.Consensus == ConsensusPolyBFT {
+		return fmt.Errorf("the validator set of a %s stack is managed by its rootchain stake, only %s stacks are supported", ConsensusPolyBFT, ConsensusIBFT)
+	}
+
+	return nil
+}
+
+// CreateValidatorSecrets generates and stores the secrets of validators first to last with the
+// secrets backend of the stack and returns their public keys
+func CreateValidatorSecrets(ctx context.Context, nsArgs string, first int, last int) ([]ValidatorKeys, error) {
+	ctx, cancel := stepContext(ctx)
+	defer cancel()
+
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	logs, err := runJob(ctx, nsArgs, newValidatorSecretsJob(nsArgs, first, last, requestBody))
+	if err != nil {
+		return nil, err
+	}
+
+	var validators []ValidatorKeys
+	for _, line := range strings.Split(logs, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 5 || fields[0] != validatorKeysPrefix {
+			continue
+		}
+
+		index, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid validator line %q: %w", line, err)
+		}
+
+		validators = append(validators, ValidatorKeys{
+			Index:     index,
+			Address:   fields[2],
+			BLSPubkey: fields[3],
+			NodeID:    fields[4],
+		})
+	}
+
+	if len(validators) != last-first+1 {
+		return nil, fmt.Errorf("the secrets job reported %d of %d validators", len(validators), last-first+1)
+	}
+
+	return validators, nil
+}
+
+// RemoveValidatorSecrets removes the secrets of validators first to last which were generated by
+// a failed run. The file backend replaces the secrets of a node when they are generated again
+func RemoveValidatorSecrets(ctx context.Context, nsArgs string, first int, last int) (string, error) {
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+
+	if requestBody.SecretsBackend == SecretsBackendFile {
+		return "Validator secrets are left to be replaced by the next run 🔑", nil
+	}
+
+	for i := first; i <= last; i++ {
+		if err := getSecretsBackend(requestBody).deleteNodeSecrets(ctx, nsArgs, i); err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("Validator secrets %d to %d are successfully removed 🔑", first, last), nil
+}
+
+// RemoveValidatorNodes removes the nodes of validators first to last together with their volumes
+func RemoveValidatorNodes(ctx context.Context, nsArgs string, first int, last int) (string, error) {
+	for i := first; i <= last; i++ {
+		if _, err := RemoveValidatorNode(ctx, nsArgs, i, false); err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("Validator nodes %d to %d are successfully removed 🕹️", first, last), nil
+}
+
+// PendingValidators returns the validators a failed add left running without their votes sealed
+func PendingValidators(ctx context.Context, nsArgs string) ([]ValidatorKeys, error) {
+	state, err := loadStackState(ctx, nsArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	return state.PendingValidators, nil
+}
+
+// SetPendingValidators records the validators whose votes are not sealed yet in the stack state,
+// so a failed add resumes the votes instead of generating new validators
+func SetPendingValidators(ctx context.Context, nsArgs string, pending []ValidatorKeys) error {
+	return updateStackState(ctx, nsArgs, func(state *stackState) {
+		state.PendingValidators = pending
+	})
+}
+
+// CreateValidatorNodes creates the node config, volume, service and statefulset of validators
+// first to last and waits until they are running
+func CreateValidatorNodes(ctx context.Context, nsArgs string, first int, last int) (string, error) {
+	ctx, cancel := stepContext(ctx)
+	defer cancel()
+
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+
+	indexes, err := ValidatorIndexes(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+
+	for i := first; i <= last; i++ {
+		if err := applyConfigMap(ctx, nsArgs, newNodeConfigMap(nsArgs, i, requestBody)); err != nil {
+			return "", err
+		}
+
+		if err := applyPVC(ctx, nsArgs, newValidatorPVC(nsArgs, i, requestBody)); err != nil {
+			return "", err
+		}
+
+		if err := applyService(ctx, nsArgs, newValidatorService(nsArgs, i, requestBody)); err != nil {
+			return "", err
+		}
+
+		if err := applyStatefulSet(ctx, nsArgs, newStatefulSet(nsArgs, nsArgs, i, requestBody)); err != nil {
+			return "", err
+		}
+	}
+
+	if err := waitForValidatorPods(ctx, nsArgs, len(indexes)+last-first+1); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Validator nodes %d to %d are successfully configured 🕹️", first, last), nil
+}
+
+// ProposeValidators has every voter propose to add or to remove the candidates and waits until
+// the votes are sealed. A candidate needs the votes of more than half of the voters, so the voters
+// are the whole validator set. The address of a candidate without one is read from its own node
+func ProposeValidators(ctx context.Context, nsArgs string, voters []int, candidates []ValidatorKeys, vote string) (string, error) {
+	ctx, cancel := stepContext(ctx)
+	defer cancel()
+
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+
+	// the votes are observed on a validator which stays in the set
+	observer := voters[0]
+	for _, voter := range voters {
+		if !isCandidate(candidates, voter) {
+			observer = voter
+			break
+		}
+	}
+
+	member := "yes"
+	if vote == VoteDrop {
+		member = "no"
+	}
+
+	var script strings.Builder
+	fmt.Fprintf(&script, `
+# in_set <address> <yes|no> reports whether the address is in the validator set or out of it
+in_set() {
+  snapshot=$(polygon-edge ibft snapshot --grpc-address %[2]s --json) || return 1
+  # the validators follow the pending votes in the snapshot
+  if echo "$snapshot" | tr -d '\n' | sed 's/.*"validators"//' | grep -qi "$1"; then in=yes; else in=no; fi
+  [ "$in" = "$2" ]
+}
+
+# wait_set <address> <yes|no> waits until the address is in the validator set or out of it
+wait_set() {
+  for attempt in $(seq 1 %[1]d);
+  do
+    if in_set "$1" "$2"; then return 0; fi
+    sleep 5
+  done
+
+  echo "the votes for $1 were not sealed within %[3]d seconds"
+  return 1
+}
+`, validatorVoteTimeout/5, validatorAddress(nsArgs, observer), validatorVoteTimeout)
+
+	for _, candidate := range candidates {
+		if candidate.Address != "" {
+			fmt.Fprintf(&script, "addr=%s\n", candidate.Address)
+		} else {
+			fmt.Fprintf(&script, "addr=$(polygon-edge ibft status --grpc-address %s | grep -io '0x[0-9a-f]\\{40\\}' | head -n 1)\n",
+				validatorAddress(nsArgs, candidate.Index))
+			fmt.Fprintf(&script, "if [ -z \"$addr\" ]; then echo \"the address of validator %d is unknown\"; exit 1; fi\n", candidate.Index)
+		}
+
+		// a candidate of an earlier run may already be voted in or out
+		fmt.Fprintf(&script, "if ! in_set $addr %s; then\n", member)
+		script.WriteString("votes=0\n")
+
+		for _, voter := range voters {
+			args := fmt.Sprintf("--grpc-address %s --addr $addr --vote %s", validatorAddress(nsArgs, voter), vote)
+
+			// validators of a genesis made by the helper job are bls validators
+			if vote == VoteAuth && !requestBody.LocalGenesis {
+				args += " --bls " + candidate.BLSPubkey
+			}
+
+			fmt.Fprintf(&script, "polygon-edge ibft propose %s && votes=$((votes+1))\n", args)
+		}
+
+		fmt.Fprintf(&script, "if [ $votes -le %d ]; then echo \"only $votes of %d validators voted %s $addr\"; exit 1; fi\n",
+			len(voters)/2, len(voters), vote)
+		script.WriteString("fi\n")
+		fmt.Fprintf(&script, "wait_set $addr %s || exit 1\n", member)
+	}
+
+	job := newValidatorJob(nsArgs, validatorProposalJob, requestBody, requestBody.Image, script.String())
+
+	if _, err := runJob(ctx, nsArgs, job); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Validator %s votes are successfully sealed 🗳️", vote), nil
+}
+
+// CheckValidatorRemoval makes sure validator i can leave the set without the remaining validators
+// losing their quorum, and unless force is set without the set tolerating fewer faulty validators
+func CheckValidatorRemoval(ctx context.Context, nsArgs string, index int, purgeSecrets bool, force bool) error {
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if err != nil {
+		return err
+	}
+
+	if purgeSecrets && requestBody.SecretsBackend == SecretsBackendFile {
+		return fmt.Errorf("the %s secrets backend keeps the secrets of every node in one file, they can not be purged per node", SecretsBackendFile)
+	}
+
+	// the vote of an earlier run was sealed, only the node is left to remove
+	pending, err := PendingRemovals(ctx, nsArgs)
+	if err != nil {
+		return err
+	} else if containsIndex(pending, index) {
+		return nil
+	}
+
+	indexes, err := ValidatorIndexes(ctx, nsArgs)
+	if err != nil {
+		return err
+	}
+
+	if !containsIndex(indexes, index) {
+		return fmt.Errorf("validator %d is not part of stack %s", index, nsArgs)
+	}
+
+	total := len(indexes)
+	if total == 1 {
+		return fmt.Errorf("validator %d is the last validator of stack %s", index, nsArgs)
+	}
+
+	if !force && !removalKeepsFaultTolerance(total) {
+		faulty := faultTolerance(total)
+
+		return fmt.Errorf("removing validator %d leaves %d validators, below the %d needed to tolerate %d faulty ones, use --force to remove it anyway",
+			index, total-1, 3*faulty+1, faulty)
+	}
+
+	// the remaining validators have to reach the quorum of the smaller set on their own
+	quorum := validatorQuorum(total - 1)
+	ready := 0
+	for _, i := range indexes {
+		if i != index && getNodeStatus(ctx, nsArgs, i, requestBody).Ready {
+			ready++
+		}
+	}
+
+	if ready < quorum {
+		return fmt.Errorf("only %d of the %d remaining validators are ready, %d are needed to keep producing blocks", ready, total-1, quorum)
+	}
+
+	return nil
+}
+
+// PendingRemovals returns the validators a failed remove voted out of the set without removing their nodes
+func PendingRemovals(ctx context.Context, nsArgs string) ([]int, error) {
+	state, err := loadStackState(ctx, nsArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	return state.PendingRemovals, nil
+}
+
+// StartValidatorRemoval records that validator i was voted out of the set before its node is
+// removed, the remaining validators vote without it from then on and a failed removal is resumed
+func StartValidatorRemoval(ctx context.Context, nsArgs string, index int) error {
+	err := updateStackState(ctx, nsArgs, func(state *stackState) {
+		if !containsIndex(state.PendingRemovals, index) {
+			state.PendingRemovals = append(state.PendingRemovals, index)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	indexes, err := ValidatorIndexes(ctx, nsArgs)
+	if err != nil {
+		return err
+	}
+
+	var remaining []int
+	for _, i := range indexes {
+		if i != index {
+			remaining = append(remaining, i)
+		}
+	}
+
+	return SetValidatorIndexes(ctx, nsArgs, remaining)
+}
+
+// FinishValidatorRemoval clears the pending removal of validator i once its node is removed
+func FinishValidatorRemoval(ctx context.Context, nsArgs string, index int) error {
+	return updateStackState(ctx, nsArgs, func(state *stackState) {
+		var pending []int
+		for _, i := range state.PendingRemovals {
+			if i != index {
+				pending = append(pending, i)
+			}
+		}
+
+		state.PendingRemovals = pending
+	})
+}
+
+// faultTolerance returns how many faulty validators a set of n validators tolerates, ibft needs
+// 3f+1 validators to tolerate f
+func faultTolerance(n int) int {
+	if n < 1 {
+		return 0
+	}
+
+	return (n - 1) / 3
+}
+
+// removalKeepsFaultTolerance reports whether a set of n validators tolerates as many faulty
+// validators without one of them, which needs n-1 to be at least 3f+1
+func removalKeepsFaultTolerance(n int) bool {
+	return n-1 >= 3*faultTolerance(n)+1
+}
+
+// validatorQuorum returns the number of validators of a set of n which seal a block, two thirds
+// of the set rounded up
+func validatorQuorum(n int) int {
+	return (2*n + 2) / 3
+}
+
+// RemoveValidatorNode deletes the statefulset, service and node config of validator i, its data
+// volume unless keepPVC is set
+func RemoveValidatorNode(ctx context.Context, nsArgs string, index int, keepPVC bool) (string, error) {
+	propagation := metav1.DeletePropagationForeground
+
+	err := config.CLIENTSET.AppsV1().StatefulSets(nsArgs).Delete(ctx, fmt.Sprintf("validator-node-%v", index),
+		metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	err = config.CLIENTSET.CoreV1().Services(nsArgs).Delete(ctx, fmt.Sprintf("validator-node%v-svc", index), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	err = config.CLIENTSET.CoreV1().ConfigMaps(nsArgs).Delete(ctx, fmt.Sprintf("validator-node%v-config", index), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+
+	if keepPVC {
+		// the index is not used again, the claim is recorded so destroy still finds it
+		claim := requestBody.validatorClaim(index)
+
+		err := updateStackState(ctx, nsArgs, func(state *stackState) {
+			for _, kept := range state.KeptClaims {
+				if kept == claim {
+					return
+				}
+			}
+
+			state.KeptClaims = append(state.KeptClaims, claim)
+		})
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("Validator node %d is successfully removed, PersistentVolumeClaim is kept 🕹️", index), nil
+	}
+
+	err = config.CLIENTSET.CoreV1().PersistentVolumeClaims(nsArgs).Delete(ctx, requestBody.validatorClaim(index), metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	return fmt.Sprintf("Validator node %d is successfully removed 🕹️", index), nil
+}
+
+// PurgeValidatorSecrets removes the secrets of validator i from the secrets backend of the stack
+func PurgeValidatorSecrets(ctx context.Context, nsArgs string, index int) (string, error) {
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+
+	if err := getSecretsBackend(requestBody).deleteNodeSecrets(ctx, nsArgs, index); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Validator %d secrets are successfully purged 🔑", index), nil
+}
+
+// ValidatorIndexes returns the indexes of the validators of the stack, 1 to total-node unless
+// validators were removed
+func ValidatorIndexes(ctx context.Context, nsArgs string) ([]int, error) {
+	ns, err := config.CLIENTSET.CoreV1().Namespaces().Get(ctx, nsArgs, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var indexes []int
+
+	if data, ok := ns.Annotations[nodeIndexesAnnotation]; ok {
+		for _, field := range strings.Split(data, ",") {
+			index, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid node index %q on namespace %s", field, nsArgs)
+			}
+
+			indexes = append(indexes, index)
+		}
+
+		return indexes, nil
+	}
+
+	totalNode, err := strconv.Atoi(ns.Labels["total-node"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid total node %q on namespace %s", ns.Labels["total-node"], nsArgs)
+	}
+
+	for i := 1; i <= totalNode; i++ {
+		indexes = append(indexes, i)
+	}
+
+	return indexes, nil
+}
+
+// SetValidatorIndexes records the validators of the stack on its namespace, total-node and the
+// stored request carry their number
+func SetValidatorIndexes(ctx context.Context, nsArgs string, indexes []int) error {
+	ns, err := config.CLIENTSET.CoreV1().Namespaces().Get(ctx, nsArgs, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	sort.Ints(indexes)
+
+	fields := make([]string, len(indexes))
+	for i, index := range indexes {
+		fields[i] = strconv.Itoa(index)
+	}
+
+	if ns.Annotations == nil {
+		ns.Annotations = map[string]string{}
+	}
+
+	ns.Labels["total-node"] = strconv.Itoa(len(indexes))
+	ns.Annotations[nodeIndexesAnnotation] = strings.Join(fields, ",")
+
+	if data, ok := ns.Annotations[stackConfigAnnotation]; ok {
+		var requestBody ConfigRequest
+		if err := json.Unmarshal([]byte(data), &requestBody); err != nil {
+			return err
+		}
+
+		requestBody.NumOfNodes = strconv.Itoa(len(indexes))
+
+		request, err := json.Marshal(requestBody)
+		if err != nil {
+			return err
+		}
+
+		ns.Annotations[stackConfigAnnotation] = string(request)
+	}
+
+	_, err = config.CLIENTSET.CoreV1().Namespaces().Update(ctx, ns, metav1.UpdateOptions{})
+
+	return err
+}
+
+// highestValidatorIndex returns the highest index a validator of the stack uses, including the
+// validators voted out whose nodes were not removed yet
+func highestValidatorIndex(ctx context.Context, nsArgs string) (int, error) {
+	indexes, err := ValidatorIndexes(ctx, nsArgs)
+	if err != nil {
+		return 0, err
+	}
+
+	pending, err := PendingRemovals(ctx, nsArgs)
+	if err != nil {
+		return 0, err
+	}
+
+	highest := 0
+	for _, index := range append(indexes, pending...) {
+		if index > highest {
+			highest = index
+		}
+	}
+
+	return highest, nil
+}
+
+// validatorAddress is the grpc address of validator i
+func validatorAddress(nsArgs string, i int) string {
+	return fmt.Sprintf("validator-node%v-svc.%s.svc.cluster.local:9632", i, nsArgs)
+}
+
+func isCandidate(candidates []ValidatorKeys, index int) bool {
+	for _, candidate := range candidates {
+		if candidate.Index == index {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsIndex(indexes []int, index int) bool {
+	for _, i := range indexes {
+		if i == index {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newValidatorSecretsJob(nsArgs string, first int, last int, requestBody ConfigRequest) *batchv1.Job {
+	backend := getSecretsBackend(requestBody)
+	tools, _ := backend.tools()
+
+	script := fmt.Sprintf(`
+%s
+%s
+set -e
+
+for i in $(seq %[3]d %[4]d);
+do
+  init_secrets ${i}
+done
+
+add_secrets %[3]d %[4]d
+
+for i in $(seq %[3]d %[4]d);
+do
+  echo "%[5]s ${i} $(jq -r '.[].address' /home/node${i}keys.json) $(jq -r '.[].bls_pubkey' /home/node${i}keys.json) $(jq -r '.[].node_id' /home/node${i}keys.json)"
+done
+`, requestBody.Images.installScript(append([]string{"jq"}, tools...)), backend.jobScript(), first, last, validatorKeysPrefix)
+
+	job := newValidatorJob(nsArgs, validatorSecretsJob, requestBody, requestBody.Images.Helper, script)
+
+	volumes, volumeMounts := backend.jobVolumes()
+	pod := &job.Spec.Template.Spec
+	pod.ServiceAccountName = backend.serviceAccount(requestBody)
+	pod.Volumes = volumes
+	pod.Containers[0].VolumeMounts = volumeMounts
+	pod.Containers[0].Env = append([]apiv1.EnvVar{
+		{
+			Name:  "NAMESPACE",
+			Value: nsArgs,
+		},
+		{
+			Name:  "STACK_ID",
+			Value: nsArgs,
+		},
+		{
+			Name:  "NUM_OF_NODES",
+			Value: strconv.Itoa(last),
+		},
+	}, backend.envs(nsArgs, requestBody)...)
+
+	return job
+}
+
+func newValidatorJob(nsArgs string, jobName string, requestBody ConfigRequest, image string, script string) *batchv1.Job {
+	// a failed attempt fails the step, the job is not retried
+	var backoffLimit int32 = 0
+
+	job := &batchv1.Job{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Job",
+			APIVersion: "batch/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName,
+			Namespace: nsArgs,
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: apiv1.PodTemplateSpec{
+				Spec: apiv1.PodSpec{
+					RestartPolicy:    apiv1.RestartPolicyNever,
+					ImagePullSecrets: requestBody.Images.pullSecrets(),
+					Containers: []apiv1.Container{
+						{
+							Name:    jobName,
+							Image:   requestBody.Images.image(image),
+							Command: []string{"/bin/sh", "-c"},
+							Args:    []string{script},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	return job
+}
+
+// runJob replaces the job of an earlier run, waits for it to succeed and returns the log of its pod
+func runJob(ctx context.Context, nsArgs string, job *batchv1.Job) (string, error) {
+	if err := deleteJob(ctx, nsArgs, job.Name); err != nil {
+		return "", err
+	}
+
+	if err := applyJob(ctx, nsArgs, job); err != nil {
+		return "", err
+	}
+
+	if err := waitForHelperJob(ctx, nsArgs, job.Name); err != nil {
+		return "", fmt.Errorf("job %s failed, see kubectl logs -n %s job/%s: %w", job.Name, nsArgs, job.Name, err)
+	}
+
+	pods, err := config.CLIENTSET.CoreV1().Pods(nsArgs).List(ctx, metav1.ListOptions{
+		LabelSelector: "job-name=" + job.Name,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != apiv1.PodSucceeded {
+			continue
+		}
+
+		logs, err := config.CLIENTSET.CoreV1().Pods(nsArgs).GetLogs(pod.Name, &apiv1.PodLogOptions{}).DoRaw(ctx)
+		if err != nil {
+			return "", err
+		}
+
+		return string(logs), nil
+	}
+
+	return "", fmt.Errorf("job %s has no succeeded pod", job.Name)
+}
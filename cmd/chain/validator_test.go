@@ -0,0 +1,71 @@
+package chain
+
+import "testing"
+
+func TestFaultTolerance(t *testing.T) {
+	tests := []struct {
+		validators int
+		faulty     int
+	}{
+		{0, 0},
+		{1, 0},
+		{2, 0},
+		{3, 0},
+		{4, 1},
+		{5, 1},
+		{6, 1},
+		{7, 2},
+		{10, 3},
+		{13, 4},
+	}
+
+	for _, test := range tests {
+		if faulty := faultTolerance(test.validators); faulty != test.faulty {
+			t.Errorf("%d validators: expected to tolerate %d faulty, got %d", test.validators, test.faulty, faulty)
+		}
+	}
+}
+
+func TestValidatorQuorum(t *testing.T) {
+	tests := []struct {
+		validators int
+		quorum     int
+	}{
+		{1, 1},
+		{2, 2},
+		{3, 2},
+		{4, 3},
+		{5, 4},
+		{6, 4},
+		{7, 5},
+		{10, 7},
+	}
+
+	for _, test := range tests {
+		if quorum := validatorQuorum(test.validators); quorum != test.quorum {
+			t.Errorf("%d validators: expected a quorum of %d, got %d", test.validators, test.quorum, quorum)
+		}
+	}
+}
+
+func TestRemovalKeepsFaultTolerance(t *testing.T) {
+	tests := []struct {
+		validators int
+		keeps      bool
+	}{
+		{2, true},
+		{3, true},
+		{4, false},
+		{5, true},
+		{6, true},
+		{7, false},
+		{8, true},
+		{10, false},
+	}
+
+	for _, test := range tests {
+		if keeps := removalKeepsFaultTolerance(test.validators); keeps != test.keeps {
+			t.Errorf("removing one of %d validators: expected keeping the fault tolerance to be %v", test.validators, test.keeps)
+		}
+	}
+}
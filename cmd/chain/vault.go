@@ -0,0 +1,303 @@
+package chain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"cli/cmd/config"
+)
+
+const (
+	// vaultStackMount is the KV v2 mount the helper job writes genesis.json and node keys to
+	vaultStackMount = "polygon-edge"
+
+	// vaultSecretsMount is the KV v2 mount polygon-edge secrets init writes validator secrets to
+	vaultSecretsMount = "secret"
+)
+
+var vaultClient = &http.Client{Timeout: 30 * time.Second}
+
+type vaultTokenResponse struct {
+	Auth struct {
+		ClientToken string `json:"client_token"`
+		Accessor    string `json:"accessor"`
+	} `json:"auth"`
+}
+
+type vaultListResponse struct {
+	Data struct {
+		Keys []string `json:"keys"`
+	} `json:"data"`
+}
+
+// IsVaultConfigured reports whether a vault url and token are available
+func IsVaultConfigured() bool {
+	return config.VaultUrl != "" && config.VaultToken != ""
+}
+
+// PurgeVaultSecrets removes every secret written for the stack from Vault
+func PurgeVaultSecrets(stackId string) (string, error) {
+	if !IsVaultConfigured() {
+		return "", errors.New("vault url and token are required to purge secrets")
+	}
+
+	for _, mount := range []string{vaultStackMount, vaultSecretsMount} {
+		if err := purgeVaultPath(mount, stackId); err != nil {
+			return "", err
+		}
+	}
+
+	return "Vault secrets are successfully purged 🔑", nil
+}
+
+// CreateStackVaultToken writes a policy limited to the secrets of the stack and issues a token
+// bound to it. The token is an orphan, so it outlives the operator token, and periodic, so it
+// lives as long as it is renewed within period. It returns the token and its accessor
+func CreateStackVaultToken(stackId string, period string) (string, string, error) {
+	if !IsVaultConfigured() {
+		return "", "", errors.New("vault url and token are required to issue a child token")
+	}
+
+	if err := writeStackVaultPolicy(stackId); err != nil {
+		return "", "", err
+	}
+
+	request, err := json.Marshal(map[string]any{
+		"policies":     []string{stackVaultPolicyName(stackId)},
+		"period":       period,
+		"no_parent":    true,
+		"renewable":    true,
+		"display_name": stackVaultPolicyName(stackId),
+		"meta":         map[string]string{"stack_id": stackId},
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	// an orphan token needs sudo on auth/token/create-orphan, or a root token
+	_, body, err := vaultRequest(http.MethodPost, "auth/token/create-orphan", request)
+	if err != nil {
+		return "", "", err
+	}
+
+	var token vaultTokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", "", err
+	}
+
+	if token.Auth.ClientToken == "" {
+		return "", "", fmt.Errorf("vault did not return a token for stack %s", stackId)
+	}
+
+	return token.Auth.ClientToken, token.Auth.Accessor, nil
+}
+
+// CreateStackVaultRole writes the policy of the stack and a kubernetes auth role granting it to
+// the service account of the stack
+func CreateStackVaultRole(stackId string, mount string, ttl string) error {
+	if !IsVaultConfigured() {
+		return errors.New("vault url and token are required to create the kubernetes auth role")
+	}
+
+	if err := writeStackVaultPolicy(stackId); err != nil {
+		return err
+	}
+
+	request, err := json.Marshal(map[string]any{
+		"bound_service_account_names":      []string{vaultServiceAccountName},
+		"bound_service_account_namespaces": []string{stackId},
+		"token_policies":                   []string{stackVaultPolicyName(stackId)},
+		"token_ttl":                        ttl,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, _, err = vaultRequest(http.MethodPost, fmt.Sprintf("auth/%s/role/%s", mount, stackVaultPolicyName(stackId)), request)
+
+	return err
+}
+
+// RevokeStackVaultAccess removes the vault role or revokes the child token of the stack, together
+// with its policy. Stacks using the operator token have nothing to revoke
+func RevokeStackVaultAccess(ctx context.Context, nsArgs string) (string, error) {
+	requestBody, err := loadStackRequest(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+
+	if requestBody.Vault.AuthMethod == VaultAuthKubernetes {
+		path := fmt.Sprintf("auth/%s/role/%s", requestBody.Vault.AuthMount, stackVaultPolicyName(nsArgs))
+		if _, _, err := vaultRequest(http.MethodDelete, path, nil); err != nil {
+			return "", err
+		}
+
+		if _, _, err := vaultRequest(http.MethodDelete, "sys/policies/acl/"+stackVaultPolicyName(nsArgs), nil); err != nil {
+			return "", err
+		}
+
+		return "Vault role is successfully removed 🔑", nil
+	}
+
+	accessor, err := getVaultTokenAccessor(ctx, nsArgs)
+	if err != nil {
+		return "", err
+	}
+
+	if accessor == "" {
+		return "Stack uses the operator vault token, nothing to revoke 🔑", nil
+	}
+
+	if err := revokeVaultToken(nsArgs, accessor); err != nil {
+		return "", err
+	}
+
+	return "Vault child token is successfully revoked 🔑", nil
+}
+
+func revokeVaultToken(stackId string, accessor string) error {
+	request, err := json.Marshal(map[string]string{"accessor": accessor})
+	if err != nil {
+		return err
+	}
+
+	// an expired token is already gone, vault answers with a bad request for its accessor
+	_, _, err = vaultRequest(http.MethodPost, "auth/token/revoke-accessor", request)
+	if err != nil && !strings.Contains(err.Error(), "invalid accessor") {
+		return err
+	}
+
+	_, _, err = vaultRequest(http.MethodDelete, "sys/policies/acl/"+stackVaultPolicyName(stackId), nil)
+
+	return err
+}
+
+func writeStackVaultPolicy(stackId string) error {
+	policy, err := json.Marshal(map[string]string{"policy": stackVaultPolicy(stackId)})
+	if err != nil {
+		return err
+	}
+
+	_, _, err = vaultRequest(http.MethodPut, "sys/policies/acl/"+stackVaultPolicyName(stackId), policy)
+
+	return err
+}
+
+func stackVaultPolicyName(stackId string) string {
+	return "polygon-edge-" + stackId
+}
+
+// stackVaultPolicy grants access to the paths the helper job and the validators use for the stack
+func stackVaultPolicy(stackId string) string {
+	var policy strings.Builder
+
+	for _, mount := range []string{vaultStackMount, vaultSecretsMount} {
+		fmt.Fprintf(&policy, `path "%s/data/%s/*" {
+  capabilities = ["create", "read", "update", "delete", "list"]
+}
+
+path "%s/metadata/%s/*" {
+  capabilities = ["read", "list", "delete"]
+}
+
+`, mount, stackId, mount, stackId)
+	}
+
+	return policy.String()
+}
+
+// writeVaultSecret writes data as the latest version of the KV v2 secret at path
+func writeVaultSecret(mount string, path string, data any) error {
+	request, err := json.Marshal(map[string]any{"data": data})
+	if err != nil {
+		return err
+	}
+
+	_, _, err = vaultRequest(http.MethodPost, fmt.Sprintf("%s/data/%s", mount, path), request)
+
+	return err
+}
+
+// purgeVaultPath recursively deletes all versions and metadata below the given KV v2 path
+func purgeVaultPath(mount string, path string) error {
+	keys, err := listVaultPath(mount, path)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		child := fmt.Sprintf("%s/%s", path, strings.TrimSuffix(key, "/"))
+
+		if strings.HasSuffix(key, "/") {
+			if err := purgeVaultPath(mount, child); err != nil {
+				return err
+			}
+		}
+
+		if err := deleteVaultMetadata(mount, child); err != nil {
+			return err
+		}
+	}
+
+	return deleteVaultMetadata(mount, path)
+}
+
+func listVaultPath(mount string, path string) ([]string, error) {
+	res, body, err := vaultRequest("LIST", fmt.Sprintf("%s/metadata/%s", mount, path), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+
+	var list vaultListResponse
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, err
+	}
+
+	return list.Data.Keys, nil
+}
+
+func deleteVaultMetadata(mount string, path string) error {
+	_, _, err := vaultRequest(http.MethodDelete, fmt.Sprintf("%s/metadata/%s", mount, path), nil)
+
+	return err
+}
+
+// vaultRequest sends a request to the Vault HTTP API, treating 404 as a valid response
+func vaultRequest(method string, path string, data []byte) (*http.Response, []byte, error) {
+	url := fmt.Sprintf("%s/v1/%s", strings.TrimSuffix(config.VaultUrl, "/"), path)
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req.Header.Set("X-Vault-Token", config.VaultToken)
+
+	res, err := vaultClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if res.StatusCode >= http.StatusBadRequest && res.StatusCode != http.StatusNotFound {
+		return nil, nil, fmt.Errorf("vault %s %s failed with status %d: %s", method, path, res.StatusCode, string(body))
+	}
+
+	return res, body, nil
+}
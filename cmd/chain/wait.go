@@ -0,0 +1,140 @@
+package chain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/cache"
+
+	"cli/cmd/config"
+)
+
+// stepContext bounds a step of a command with the --timeout of the cli
+func stepContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, config.StepTimeout)
+}
+
+// waitFor runs check once the informers synced and again every time one of them sees a change
+// or resyncs, until check reports done, fails or the context ends. Checks which depend on more
+// than the watched objects, like events or elapsed time, rely on the resync of the informers
+func waitFor(ctx context.Context, check func() (bool, error), informers ...cache.SharedIndexInformer) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	changed := make(chan struct{}, 1)
+	notify := func() {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	}
+
+	synced := make([]cache.InformerSynced, 0, len(informers))
+	for _, informer := range informers {
+		_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    func(interface{}) { notify() },
+			UpdateFunc: func(interface{}, interface{}) { notify() },
+			DeleteFunc: func(interface{}) { notify() },
+		})
+		if err != nil {
+			return err
+		}
+
+		go informer.Run(ctx.Done())
+		synced = append(synced, informer.HasSynced)
+	}
+
+	if !cache.WaitForCacheSync(ctx.Done(), synced...) {
+		return waitError(ctx)
+	}
+
+	for {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return waitError(ctx)
+		case <-changed:
+		}
+	}
+}
+
+// waitForDeletion waits until the object of the given namespace and name is gone from an informer
+// narrowed down to it
+func waitForDeletion(ctx context.Context, informer cache.SharedIndexInformer, nsArgs string, name string) error {
+	return waitFor(ctx, func() (bool, error) {
+		_, exists, err := storedObject(informer, nsArgs, name)
+
+		return !exists, err
+	}, informer)
+}
+
+// waitError tells a step which ran out of time apart from one cancelled by an interrupt
+func waitError(ctx context.Context) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("step timed out after %s, a longer --timeout may help: %w", config.StepTimeout, ctx.Err())
+	}
+
+	return ctx.Err()
+}
+
+// newInformer watches the resource of client in the namespace, an empty namespace watches a
+// cluster scoped resource. A resync of zero only reports changes
+func newInformer(client cache.Getter, resource string, nsArgs string, object runtime.Object, resync time.Duration, options func(*metav1.ListOptions)) cache.SharedIndexInformer {
+	listWatch := cache.NewFilteredListWatchFromClient(client, resource, nsArgs, options)
+
+	return cache.NewSharedIndexInformer(listWatch, object, resync, cache.Indexers{})
+}
+
+// byName narrows an informer down to the object of the given name
+func byName(name string) func(*metav1.ListOptions) {
+	return func(options *metav1.ListOptions) {
+		options.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+	}
+}
+
+// byLabels narrows an informer down to the objects with the given labels
+func byLabels(set map[string]string) func(*metav1.ListOptions) {
+	return func(options *metav1.ListOptions) {
+		options.LabelSelector = labels.SelectorFromSet(set).String()
+	}
+}
+
+// storedObject returns the object of the given namespace and name from the store of an informer
+func storedObject(informer cache.SharedIndexInformer, nsArgs string, name string) (interface{}, bool, error) {
+	key := name
+	if nsArgs != "" {
+		key = nsArgs + "/" + name
+	}
+
+	return informer.GetStore().GetByKey(key)
+}
+
+// pollFor runs check every interval until it reports done, fails or the context ends. It is
+// meant for state outside of the cluster api, like the json-rpc of a validator
+func pollFor(ctx context.Context, interval time.Duration, check func() (bool, error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		done, err := check()
+		if err != nil || done {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return waitError(ctx)
+		case <-ticker.C:
+		}
+	}
+}
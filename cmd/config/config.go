@@ -3,6 +3,7 @@ package config
 import (
 	"flag"
 	"log"
+	"time"
 
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -11,6 +12,15 @@ import (
 
 var CLIENTSET *kubernetes.Clientset
 
+// RESTCONFIG is the config of CLIENTSET, port forwards and execs into pods are built from it
+var RESTCONFIG *rest.Config
+
+// DefaultStepTimeout bounds each step of a command that waits for the cluster
+const DefaultStepTimeout = 10 * time.Minute
+
+// StepTimeout is how long a single step of a command may wait for the cluster
+var StepTimeout = DefaultStepTimeout
+
 var isK8is bool = false
 var VaultToken string = ""
 var VaultUrl string = ""
@@ -43,6 +53,8 @@ func authK8s() *kubernetes.Clientset {
 		log.Panicf("Error while building config %s", err.Error())
 	}
 
+	RESTCONFIG = config
+
 	CLIENTSET, err = kubernetes.NewForConfig(config)
 	if err != nil {
 		log.Panicln("Error while creating K8 client", err.Error())
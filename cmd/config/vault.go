@@ -0,0 +1,97 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// the environment variables read by the vault cli as well
+	VaultAddrEnv  = "VAULT_ADDR"
+	VaultTokenEnv = "VAULT_TOKEN"
+
+	// ConfigFileEnv overrides the location of the cli config file
+	ConfigFileEnv = "POLYGON_SUPERNET_CONFIG"
+
+	configFileName = ".polygon-supernet-cli.yaml"
+)
+
+// FileConfig is the content of the cli config file
+type FileConfig struct {
+	VaultUrl   string `json:"vaultUrl,omitempty"`
+	VaultToken string `json:"vaultToken,omitempty"`
+}
+
+// LoadVaultConfig fills the vault settings that were not passed as flags, first from the
+// VAULT_ADDR / VAULT_TOKEN environment variables and then from the cli config file
+func LoadVaultConfig() error {
+	if VaultUrl == "" {
+		VaultUrl = os.Getenv(VaultAddrEnv)
+	}
+
+	if VaultToken == "" {
+		VaultToken = os.Getenv(VaultTokenEnv)
+	}
+
+	if VaultUrl != "" && VaultToken != "" {
+		return nil
+	}
+
+	fileConfig, err := readConfigFile()
+	if err != nil {
+		return err
+	}
+
+	if VaultUrl == "" {
+		VaultUrl = fileConfig.VaultUrl
+	}
+
+	if VaultToken == "" {
+		VaultToken = fileConfig.VaultToken
+	}
+
+	return nil
+}
+
+// ConfigFilePath returns $POLYGON_SUPERNET_CONFIG, or ~/.polygon-supernet-cli.yaml by default
+func ConfigFilePath() string {
+	if path := os.Getenv(ConfigFileEnv); path != "" {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, configFileName)
+}
+
+func readConfigFile() (*FileConfig, error) {
+	fileConfig := &FileConfig{}
+
+	path := ConfigFilePath()
+	if path == "" {
+		return fileConfig, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		// the config file is optional unless it was pointed to explicitly
+		if errors.Is(err, os.ErrNotExist) && os.Getenv(ConfigFileEnv) == "" {
+			return fileConfig, nil
+		}
+
+		return nil, err
+	}
+
+	if err := yaml.UnmarshalStrict(data, fileConfig); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	return fileConfig, nil
+}
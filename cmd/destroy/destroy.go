@@ -0,0 +1,182 @@
+package destroy
+
+import (
+	"fmt"
+	"time"
+
+	"cli/cmd/chain"
+	"cli/cmd/helper"
+
+	"github.com/briandowns/spinner"
+	"github.com/spf13/cobra"
+)
+
+type destroyParams struct {
+	KeepPVC            bool
+	PurgeVault         bool
+	DeleteStorageClass bool
+}
+
+var (
+	params = &destroyParams{}
+)
+
+const (
+	KeepPVC            = "keep-pvc"
+	PurgeVault         = "purge-vault"
+	DeleteStorageClass = "delete-storage-class"
+)
+
+func (p *destroyParams) getResult(stakeId string) helper.CommandResult {
+	return &helper.DestroyResult{
+		Message: fmt.Sprintf("\nStack %s destroyed successfully \n", stakeId),
+	}
+}
+
+func GetCommand() *cobra.Command {
+	destroyCmd := &cobra.Command{
+		Use:     "destroy <stake-id>",
+		Short:   "Tears down a stack created by genesis",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: preRunCommand,
+		Run:     runCommand,
+	}
+
+	setFlags(destroyCmd)
+
+	return destroyCmd
+}
+
+func setFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(
+		&params.KeepPVC,
+		KeepPVC,
+		false,
+		"keep the validator data volumes (the namespace is kept as well)",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.PurgeVault,
+		PurgeVault,
+		false,
+		"remove the stack secrets from vault",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.DeleteStorageClass,
+		DeleteStorageClass,
+		false,
+		"remove the shared polygonsc storage class if no other stack uses it",
+	)
+}
+
+func preRunCommand(cmd *cobra.Command, args []string) error {
+	return chain.ValidateStack(cmd.Context(), args[0])
+}
+
+func runCommand(cmd *cobra.Command, args []string) {
+	outputter := helper.InitializeOutputter(cmd)
+	defer outputter.WriteOutput()
+
+	namespace := args[0]
+
+	s := spinner.New(spinner.CharSets[14], 110*time.Millisecond, spinner.WithColor("cyan"))
+
+	stackId, err := chain.GetStakeId(cmd.Context(), namespace)
+	if err != nil || stackId == "" {
+		stackId = namespace
+	}
+
+	// the secrets backend is read from the namespace, before it is removed
+	usesVault, err := chain.UsesVault(cmd.Context(), namespace)
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	fmt.Println("\n ")
+	s.Suffix = " Running..."
+	s.Start()
+	defer s.Stop()
+
+	result, err := chain.DeleteLoadBalancer(cmd.Context(), namespace)
+	if err != nil {
+		helper.EmitCmd(s, "LoadBalancer removal is failed", false)
+		outputter.SetError(err)
+		return
+	} else {
+		helper.EmitCmd(s, result, true)
+	}
+
+	result, err = chain.DeleteStateFulSet(cmd.Context(), namespace)
+	if err != nil {
+		helper.EmitCmd(s, "Statefulset removal is failed", false)
+		outputter.SetError(err)
+		return
+	} else {
+		helper.EmitCmd(s, result, true)
+	}
+
+	result, err = chain.DeletePVCAndService(cmd.Context(), namespace, params.KeepPVC)
+	if err != nil {
+		helper.EmitCmd(s, "PersistentVolumeClaim removal is failed", false)
+		outputter.SetError(err)
+		return
+	} else {
+		helper.EmitCmd(s, result, true)
+	}
+
+	result, err = chain.DeleteNodeConfigMap(cmd.Context(), namespace)
+	if err != nil {
+		helper.EmitCmd(s, "Validator node config removal is failed", false)
+		outputter.SetError(err)
+		return
+	} else {
+		helper.EmitCmd(s, result, true)
+	}
+
+	// the vault access of the stack is recorded in the namespace, so it is revoked before the namespace goes
+	if usesVault && chain.IsVaultConfigured() {
+		result, err = chain.RevokeStackVaultAccess(cmd.Context(), namespace)
+		if err != nil {
+			helper.EmitCmd(s, "Vault access revocation is failed", false)
+			outputter.SetError(err)
+			return
+		} else {
+			helper.EmitCmd(s, result, true)
+		}
+	}
+
+	result, err = chain.DeleteConfigMap(cmd.Context(), namespace, params.KeepPVC)
+	if err != nil {
+		helper.EmitCmd(s, "Namespace removal is failed", false)
+		outputter.SetError(err)
+		return
+	} else {
+		helper.EmitCmd(s, result, true)
+	}
+
+	if params.DeleteStorageClass {
+		result, err = chain.DeleteStorageClass(cmd.Context(), namespace)
+		if err != nil {
+			helper.EmitCmd(s, "StorageClass removal is failed", false)
+			outputter.SetError(err)
+			return
+		} else {
+			helper.EmitCmd(s, result, true)
+		}
+	}
+
+	if params.PurgeVault && usesVault {
+		result, err = chain.PurgeVaultSecrets(stackId)
+		if err != nil {
+			helper.EmitCmd(s, "Vault secrets purge is failed", false)
+			outputter.SetError(err)
+			return
+		} else {
+			helper.EmitCmd(s, result, true)
+		}
+	}
+
+	outputter.SetCommandResult(params.getResult(namespace))
+}
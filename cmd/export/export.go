@@ -0,0 +1,20 @@
+package export
+
+import (
+	"cli/cmd/export/helm"
+
+	"github.com/spf13/cobra"
+)
+
+func GetCommand() *cobra.Command {
+	exportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Top level command for exporting a stack to other deployment formats",
+	}
+
+	exportCmd.AddCommand(
+		helm.GetCommand(),
+	)
+
+	return exportCmd
+}
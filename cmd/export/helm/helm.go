@@ -0,0 +1,76 @@
+package helm
+
+import (
+	"fmt"
+
+	"cli/cmd/chain"
+	"cli/cmd/helper"
+
+	"github.com/spf13/cobra"
+)
+
+type helmParams struct {
+	OutputDir string
+}
+
+var (
+	params = &helmParams{}
+)
+
+const (
+	OutputDir = "output-dir"
+)
+
+func GetCommand() *cobra.Command {
+	helmCmd := &cobra.Command{
+		Use:     "helm <stake-id>",
+		Short:   "Exports a stack as a parameterized Helm chart",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: preRunCommand,
+		Run:     runCommand,
+	}
+
+	setFlags(helmCmd)
+
+	return helmCmd
+}
+
+func setFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&params.OutputDir,
+		OutputDir,
+		"",
+		"the directory to write the chart to (default ./<stake-id>-chart)",
+	)
+}
+
+func preRunCommand(cmd *cobra.Command, args []string) error {
+	if params.OutputDir == "" {
+		params.OutputDir = fmt.Sprintf("%s-chart", args[0])
+	}
+
+	return chain.ValidateStack(cmd.Context(), args[0])
+}
+
+func runCommand(cmd *cobra.Command, args []string) {
+	outputter := helper.InitializeOutputter(cmd)
+	defer outputter.WriteOutput()
+
+	requestBody, err := chain.GetStackRequest(cmd.Context(), args[0])
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	files, err := chain.ExportHelmChart(requestBody, params.OutputDir)
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	outputter.SetCommandResult(&helper.ExportResult{
+		Format:    "helm",
+		OutputDir: params.OutputDir,
+		Files:     files,
+	})
+}
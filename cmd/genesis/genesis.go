@@ -2,6 +2,7 @@ package genesis
 
 import (
 	"cli/cmd/helper"
+	"context"
 	"errors"
 	"strings"
 	"time"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/briandowns/spinner"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/google/uuid"
 	"github.com/spf13/cobra"
 )
 
@@ -21,8 +23,37 @@ type genesisParams struct {
 	EpochSize       string
 	NodePremineFund string
 	Premine         chain.PremineAllo
-	VaultUrl        string
-	VaultToken      string
+	KeepOnFailure   bool
+	Resume          string
+	DryRun          bool
+	OutputDir       string
+	StakeId         string
+	File            string
+	Consensus       string
+	ValidatorStake  string
+	BridgeJSONRPC   string
+	BlockConfirm    string
+	ChainID         string
+	AutoChainID     bool
+	VaultChildToken bool
+	VaultTokenTTL   string
+	VaultAuth       string
+	SecretsBackend  string
+	VaultAuthMount  string
+	LocalGenesis    bool
+	Image           string
+	HelperImage     string
+	FetchImage      string
+	Registry        string
+	PullSecrets     []string
+	AirGapped       bool
+	HeadlessSvc     bool
+	StorageClass    string
+	StoragePreset   string
+	StorageSize     string
+
+	// spec holds the infrastructure settings loaded from --file
+	spec chain.ConfigRequest
 }
 
 var (
@@ -67,14 +98,39 @@ func (p *PremineAllo) Set(value string) error {
 }
 
 const (
-	VaultToken      = "valut-token"
-	VaultUrl        = "valut-url"
 	Name            = "name"
 	TotalNode       = "totalNode"
 	GasLimit        = "gasLimit"
 	EpochSize       = "epochSize"
 	NodePremineFund = "nodePremineFund"
 	Premine         = "premine"
+	KeepOnFailure   = "keep-on-failure"
+	Resume          = "resume"
+	OutputDir       = "output-dir"
+	StakeId         = "stake-id"
+	File            = "file"
+	Consensus       = "consensus"
+	ValidatorStake  = "validator-stake"
+	BridgeJSONRPC   = "bridge-json-rpc"
+	BlockConfirm    = "num-block-confirmations"
+	ChainID         = "chain-id"
+	AutoChainID     = "auto-chain-id"
+	VaultChildToken = "vault-child-token"
+	VaultTokenTTL   = "vault-token-ttl"
+	VaultAuth       = "vault-auth"
+	SecretsBackend  = "secrets-backend"
+	VaultAuthMount  = "vault-auth-mount"
+	LocalGenesis    = "local-genesis"
+	Image           = "image"
+	HelperImage     = "helper-image"
+	FetchImage      = "fetch-image"
+	Registry        = "registry"
+	PullSecret      = "image-pull-secret"
+	AirGapped       = "air-gapped"
+	HeadlessSvc     = "headless-services"
+	StorageClass    = "storage-class"
+	StoragePreset   = "storage-preset"
+	StorageSize     = "storage-size"
 )
 
 func (p *genesisParams) getResult() helper.CommandResult {
@@ -83,6 +139,14 @@ func (p *genesisParams) getResult() helper.CommandResult {
 	}
 }
 
+func (p *genesisParams) getRenderResult(manifests string, files []string) helper.CommandResult {
+	return &helper.RenderResult{
+		Manifests: manifests,
+		OutputDir: p.OutputDir,
+		Files:     files,
+	}
+}
+
 func GetCommand() *cobra.Command {
 	genesisCmd := &cobra.Command{
 		Use:     "genesis",
@@ -93,9 +157,41 @@ func GetCommand() *cobra.Command {
 
 	setFlags(genesisCmd)
 
+	genesisCmd.Flags().BoolVar(
+		&params.DryRun,
+		helper.DryRunFlag,
+		false,
+		"render the manifests instead of creating them on the cluster, the vault token is left as a placeholder",
+	)
+
 	return genesisCmd
 }
 
+// GetRenderCommand returns the render command, which is the same as genesis --dry-run
+func GetRenderCommand() *cobra.Command {
+	renderCmd := &cobra.Command{
+		Use:   "render",
+		Short: "Renders the manifests genesis would create as YAML, without a cluster",
+		Long: `Renders the manifests genesis would create as YAML, without a cluster.
+
+The manifests are meant to be committed, so they never carry credentials. The token of the
+vaultconfig-secret of the vault secrets backend is the placeholder replace-with-vault-token,
+and the passphrase of the secrets-file-passphrase secret of the file secrets backend is the
+placeholder replace-with-secrets-passphrase. Set the real values when the secrets are applied.`,
+		Annotations: map[string]string{helper.OfflineAnnotation: "true"},
+		PreRunE: func(cmd *cobra.Command, args []string) error {
+			params.DryRun = true
+
+			return preRunCommand(cmd, args)
+		},
+		Run: runCommand,
+	}
+
+	setFlags(renderCmd)
+
+	return renderCmd
+}
+
 func setFlags(cmd *cobra.Command) {
 	cmd.Flags().StringVar(
 		&params.Name,
@@ -139,22 +235,224 @@ func setFlags(cmd *cobra.Command) {
 		"the premined accounts and balances (format: [<address>:<balance>]).",
 	)
 
+	cmd.Flags().BoolVar(
+		&params.KeepOnFailure,
+		KeepOnFailure,
+		false,
+		"keep the partially created stack when a step fails, for debugging",
+	)
+
+	cmd.Flags().StringVar(
+		&params.Resume,
+		Resume,
+		"",
+		"the stake id of an interrupted genesis run to finish, existing objects are adopted",
+	)
+
+	cmd.Flags().StringVar(
+		&params.OutputDir,
+		OutputDir,
+		"",
+		"write the rendered manifests into this directory instead of stdout (dry-run only)",
+	)
+
 	cmd.Flags().StringVar(
-		&params.VaultToken,
-		VaultToken,
+		&params.StakeId,
+		StakeId,
 		"",
-		"secure valut path",
+		"a fixed stake id for the rendered manifests, a random one is used by default (dry-run only)",
+	)
+
+	cmd.Flags().StringVarP(
+		&params.File,
+		File,
+		"f",
+		"",
+		"a YAML or JSON stack spec, flags override the fields it sets (see spec init)",
+	)
+
+	cmd.Flags().StringVar(
+		&params.Consensus,
+		Consensus,
+		chain.ConsensusIBFT,
+		"the consensus protocol to use (ibft or polybft), polybft needs an --image whose secrets init prints bls signatures",
 	)
 
 	cmd.Flags().StringVar(
-		&params.VaultUrl,
-		VaultUrl,
+		&params.ValidatorStake,
+		ValidatorStake,
 		"",
-		"secure valut path",
+		"the stake of every validator (polybft only)",
+	)
+
+	cmd.Flags().StringVar(
+		&params.BridgeJSONRPC,
+		BridgeJSONRPC,
+		"",
+		"the rootchain json-rpc endpoint, enables the bridge and the relayer on the first validator (polybft only)",
+	)
+
+	cmd.Flags().StringVar(
+		&params.BlockConfirm,
+		BlockConfirm,
+		"64",
+		"the number of rootchain blocks the relayer waits for before processing events",
+	)
+
+	cmd.Flags().StringVar(
+		&params.ChainID,
+		ChainID,
+		"",
+		"the chain id of the network, must not be used by another stack (default 51001)",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.AutoChainID,
+		AutoChainID,
+		false,
+		"assign the lowest chain id not used by another stack",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.VaultChildToken,
+		VaultChildToken,
+		false,
+		"give the stack a periodic orphan token limited to its own secrets instead of the operator vault token, issuing it needs sudo on auth/token/create-orphan",
+	)
+
+	cmd.Flags().StringVar(
+		&params.VaultTokenTTL,
+		VaultTokenTTL,
+		"",
+		"the renewal period of the child token, a cron job of the stack renews it (default 768h), or the lifetime of the kubernetes login tokens (default 1h)",
+	)
+
+	cmd.Flags().StringVar(
+		&params.SecretsBackend,
+		SecretsBackend,
+		"",
+		fmt.Sprintf("where the genesis and the validator secrets are kept: %s, %s secrets of the stack namespace, or %s, an encrypted file on a volume shared by all pods for single node dev clusters (default %s)",
+			chain.SecretsBackendVault, chain.SecretsBackendKubernetes, chain.SecretsBackendFile, chain.SecretsBackendVault),
+	)
+
+	cmd.Flags().StringVar(
+		&params.VaultAuth,
+		VaultAuth,
+		"",
+		fmt.Sprintf("how the stack authenticates to vault: %s, or %s to log in with a per-stack service account and vault role (default %s)",
+			chain.VaultAuthToken, chain.VaultAuthKubernetes, chain.VaultAuthToken),
+	)
+
+	cmd.Flags().StringVar(
+		&params.VaultAuthMount,
+		VaultAuthMount,
+		"",
+		"the path the vault kubernetes auth method is enabled at (default kubernetes)",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.LocalGenesis,
+		LocalGenesis,
+		false,
+		"generate the ecdsa validator keys, the libp2p node ids and the ibft genesis.json in the cli instead of a helper job",
+	)
+
+	cmd.Flags().StringVar(
+		&params.Image,
+		Image,
+		"",
+		"the polygon-edge image of the validators (default 0xpolygon/polygon-edge:0.9.0)",
+	)
+
+	cmd.Flags().StringVar(
+		&params.HelperImage,
+		HelperImage,
+		"",
+		"the image of the helper job, it needs polygon-edge, jq and curl (default the node image)",
+	)
+
+	cmd.Flags().StringVar(
+		&params.FetchImage,
+		FetchImage,
+		"",
+		"the image of the init container fetching the validator secrets (default alpine:3.18)",
+	)
+
+	cmd.Flags().StringVar(
+		&params.Registry,
+		Registry,
+		"",
+		"the registry every image of the stack is pulled from",
+	)
+
+	cmd.Flags().StringSliceVar(
+		&params.PullSecrets,
+		PullSecret,
+		nil,
+		"a [namespace/]name docker registry secret copied into the stack namespace and used to pull the images",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.AirGapped,
+		AirGapped,
+		false,
+		"never install tools in the helper job and the init containers at runtime, the images have to ship them",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.HeadlessSvc,
+		HeadlessSvc,
+		false,
+		"make the validator-node<i>-svc services headless, resolving to the pod of their validator",
+	)
+
+	cmd.Flags().StringVar(
+		&params.StorageClass,
+		StorageClass,
+		"",
+		"an existing storage class for the validator volumes (default polygonsc, created from --storage-preset)",
+	)
+
+	cmd.Flags().StringVar(
+		&params.StoragePreset,
+		StoragePreset,
+		"",
+		fmt.Sprintf("the provisioner the polygonsc storage class is created with: %s (default gce)", strings.Join(chain.StoragePresets(), ", ")),
+	)
+
+	cmd.Flags().StringVar(
+		&params.StorageSize,
+		StorageSize,
+		"",
+		"the size of every validator data volume (default 10Gi)",
 	)
 }
 
-func validateFlags() error {
+func validateFlags(ctx context.Context) error {
+	if params.DryRun && params.Resume != "" {
+		return errors.New("dry-run can not be combined with resume")
+	}
+
+	if params.Resume != "" {
+		return chain.ValidateStack(ctx, params.Resume)
+	}
+
+	if params.AutoChainID && params.ChainID != "" {
+		return errors.New("chain-id can not be combined with auto-chain-id")
+	}
+
+	if params.AutoChainID && params.DryRun {
+		return errors.New("auto-chain-id needs a cluster to look up the used chain ids")
+	}
+
+	if params.LocalGenesis && params.DryRun {
+		return errors.New("local-genesis generates the validator keys against a cluster and can not be combined with dry-run")
+	}
+
+	if params.VaultChildToken && params.DryRun {
+		return errors.New("vault-child-token needs vault to issue the token")
+	}
+
 	if params.Name == "" {
 		return errors.New("Chain name is required")
 	}
@@ -167,11 +465,127 @@ func validateFlags() error {
 		return errors.New("Node-Premine amount is required")
 	}
 
+	return params.getRequest().Validate()
+}
+
+// loadSpec fills every field that was not set by a flag from the stack spec file
+func loadSpec(cmd *cobra.Command) error {
+	spec, err := chain.LoadStackSpec(params.File)
+	if err != nil {
+		return err
+	}
+
+	fields := []struct {
+		flag  string
+		param *string
+		value string
+	}{
+		{Name, &params.Name, spec.Spec.Name},
+		{TotalNode, &params.TotalNode, spec.Spec.NumOfNodes},
+		{GasLimit, &params.GasLimit, spec.Spec.GasLimit},
+		{EpochSize, &params.EpochSize, spec.Spec.EpochSize},
+		{NodePremineFund, &params.NodePremineFund, spec.Spec.NodePremineAmount},
+		{Consensus, &params.Consensus, spec.Spec.Consensus},
+		{ValidatorStake, &params.ValidatorStake, spec.Spec.ValidatorStake},
+		{BridgeJSONRPC, &params.BridgeJSONRPC, spec.Spec.BridgeJSONRPC},
+		{BlockConfirm, &params.BlockConfirm, spec.Spec.BlockConfirmation},
+		{ChainID, &params.ChainID, spec.Spec.ChainID},
+		{VaultTokenTTL, &params.VaultTokenTTL, spec.Spec.Vault.TokenTTL},
+		{SecretsBackend, &params.SecretsBackend, spec.Spec.SecretsBackend},
+		{VaultAuth, &params.VaultAuth, spec.Spec.Vault.AuthMethod},
+		{VaultAuthMount, &params.VaultAuthMount, spec.Spec.Vault.AuthMount},
+		{Image, &params.Image, spec.Spec.Image},
+		{HelperImage, &params.HelperImage, spec.Spec.Images.Helper},
+		{FetchImage, &params.FetchImage, spec.Spec.Images.Fetch},
+		{Registry, &params.Registry, spec.Spec.Images.Registry},
+		{StorageClass, &params.StorageClass, spec.Spec.Storage.ClassName},
+		{StoragePreset, &params.StoragePreset, spec.Spec.Storage.Preset},
+		{StorageSize, &params.StorageSize, spec.Spec.Storage.Size},
+	}
+
+	for _, field := range fields {
+		if !cmd.Flags().Changed(field.flag) && field.value != "" {
+			*field.param = field.value
+		}
+	}
+
+	if !cmd.Flags().Changed(Premine) && len(spec.Spec.Premine) > 0 {
+		premine = spec.Spec.Premine
+	}
+
+	if !cmd.Flags().Changed(VaultChildToken) {
+		params.VaultChildToken = spec.Spec.Vault.ChildToken
+	}
+
+	if !cmd.Flags().Changed(LocalGenesis) {
+		params.LocalGenesis = spec.Spec.LocalGenesis
+	}
+
+	if !cmd.Flags().Changed(PullSecret) && len(spec.Spec.Images.PullSecrets) > 0 {
+		params.PullSecrets = spec.Spec.Images.PullSecrets
+	}
+
+	if !cmd.Flags().Changed(AirGapped) {
+		params.AirGapped = spec.Spec.Images.AirGapped
+	}
+
+	if !cmd.Flags().Changed(HeadlessSvc) {
+		params.HeadlessSvc = spec.Spec.HeadlessServices
+	}
+
+	params.spec = spec.Spec
+
 	return nil
 }
 
+func (p *genesisParams) getRequest() chain.ConfigRequest {
+	return chain.ConfigRequest{
+		Name:              p.Name,
+		NumOfNodes:        p.TotalNode,
+		GasLimit:          p.GasLimit,
+		EpochSize:         p.EpochSize,
+		NodePremineAmount: p.NodePremineFund,
+		Premine:           premine,
+		Image:             p.Image,
+		Storage: chain.StorageConfig{
+			ClassName: p.StorageClass,
+			Preset:    p.StoragePreset,
+			Size:      p.StorageSize,
+		},
+		Resources:         p.spec.Resources,
+		ServiceType:       p.spec.ServiceType,
+		HeadlessServices:  p.HeadlessSvc,
+		Consensus:         p.Consensus,
+		ValidatorStake:    p.ValidatorStake,
+		BridgeJSONRPC:     p.BridgeJSONRPC,
+		BlockConfirmation: p.BlockConfirm,
+		ChainID:           p.ChainID,
+		SecretsBackend:    p.SecretsBackend,
+		Vault: chain.VaultConfig{
+			AuthMethod: p.VaultAuth,
+			AuthMount:  p.VaultAuthMount,
+			ChildToken: p.VaultChildToken,
+			TokenTTL:   p.VaultTokenTTL,
+		},
+		Images: chain.ImageConfig{
+			Helper:      p.HelperImage,
+			Fetch:       p.FetchImage,
+			Registry:    p.Registry,
+			PullSecrets: p.PullSecrets,
+			AirGapped:   p.AirGapped,
+		},
+		LocalGenesis: p.LocalGenesis,
+	}
+}
+
 func preRunCommand(cmd *cobra.Command, _ []string) error {
-	if err := validateFlags(); err != nil {
+	if params.File != "" {
+		if err := loadSpec(cmd); err != nil {
+			return err
+		}
+	}
+
+	if err := validateFlags(cmd.Context()); err != nil {
 		return err
 	}
 
@@ -186,14 +600,7 @@ func runCommand(cmd *cobra.Command, _ []string) {
 
 	s := spinner.New(spinner.CharSets[14], 110*time.Millisecond, spinner.WithColor("cyan"))
 
-	req := chain.ConfigRequest{
-		Name:              cmd.Flag(Name).Value.String(),
-		NumOfNodes:        cmd.Flag(TotalNode).Value.String(),
-		GasLimit:          cmd.Flag(GasLimit).Value.String(),
-		EpochSize:         cmd.Flag(EpochSize).Value.String(),
-		NodePremineAmount: cmd.Flag(NodePremineFund).Value.String(),
-		Premine:           premine,
-	}
+	req := params.getRequest()
 
 	for _, person := range premine {
 
@@ -204,70 +611,194 @@ func runCommand(cmd *cobra.Command, _ []string) {
 		}
 	}
 
+	if params.DryRun {
+		runDryRun(outputter, req)
+		return
+	}
+
+	if params.Resume == "" {
+		if req, err = resolveChainId(cmd.Context(), req); err != nil {
+			outputter.SetError(err)
+			return
+		}
+	}
+
 	fmt.Println("\n ")
 	s.Suffix = " Running..."
 	s.Start()
 
-	namespace, response, err := chain.CreateConfigMap(req)
+	rollback := chain.NewRollback()
+
+	var (
+		namespace string
+		response  string
+	)
+
+	if params.Resume != "" {
+		namespace = params.Resume
+		response, err = chain.ResumeConfigMap(cmd.Context(), namespace)
+	} else {
+		namespace, response, err = chain.CreateConfigMap(cmd.Context(), req)
+	}
+
+	if namespace != "" && params.Resume == "" {
+		rollback.Add("Initialize-Crypto", func(ctx context.Context) (string, error) {
+			usesVault, err := chain.UsesVault(ctx, namespace)
+			if err != nil {
+				return "", err
+			}
+
+			if chain.IsVaultConfigured() && usesVault {
+				if _, err := chain.RevokeStackVaultAccess(ctx, namespace); err != nil {
+					return "", err
+				}
+
+				if _, err := chain.PurgeVaultSecrets(namespace); err != nil {
+					return "", err
+				}
+			}
+
+			return chain.DeleteConfigMap(ctx, namespace, false)
+		})
+	}
+
 	if err != nil {
-		emitCmd(s, "Initialize-Crypto is failed", false)
-		outputter.SetError(err)
+		abort(s, outputter, rollback, "Initialize-Crypto is failed", err)
 		return
 	} else {
-		emitCmd(s, response, true)
+		helper.EmitCmd(s, response, true)
 	}
 
-	result, err := chain.CreateNodeConfigMap(namespace)
+	rollback.Add("Validator node config", func(ctx context.Context) (string, error) {
+		return chain.DeleteNodeConfigMap(ctx, namespace)
+	})
+
+	result, err := chain.CreateNodeConfigMap(cmd.Context(), namespace)
 	if err != nil {
-		emitCmd(s, "Validator node config is failed", false)
-		outputter.SetError(err)
+		abort(s, outputter, rollback, "Validator node config is failed", err)
 		return
 	} else {
-		emitCmd(s, result, true)
+		helper.EmitCmd(s, result, true)
 	}
 
-	result, err = chain.CreateStorageClassAndPVC(namespace)
+	rollback.Add("PersistentVolumeClaim", func(ctx context.Context) (string, error) {
+		return chain.DeletePVCAndService(ctx, namespace, false)
+	})
+
+	result, err = chain.CreateStorageClassAndPVC(cmd.Context(), namespace)
 	if err != nil {
-		emitCmd(s, "PersistentVolumeClaim config is failed", false)
-		outputter.SetError(err)
+		abort(s, outputter, rollback, "PersistentVolumeClaim config is failed", err)
 		return
 	} else {
-		emitCmd(s, result, true)
+		helper.EmitCmd(s, result, true)
 	}
 
-	result, err = chain.CreateStateFulSet(namespace)
+	rollback.Add("Statefulset", func(ctx context.Context) (string, error) {
+		return chain.DeleteStateFulSet(ctx, namespace)
+	})
+
+	result, err = chain.CreateStateFulSet(cmd.Context(), namespace)
 	if err != nil {
-		emitCmd(s, "Statefulset config is failed", false)
-		outputter.SetError(err)
+		abort(s, outputter, rollback, "Statefulset config is failed", err)
 		return
 	} else {
-		emitCmd(s, result, true)
+		helper.EmitCmd(s, result, true)
 	}
 
-	result, err = chain.CreateLoadBalancer(namespace)
+	rollback.Add("LoadBalancer", func(ctx context.Context) (string, error) {
+		return chain.DeleteLoadBalancer(ctx, namespace)
+	})
+
+	result, err = chain.CreateLoadBalancer(cmd.Context(), namespace)
 	if err != nil {
-		emitCmd(s, "LoadBalancer config is failed", false)
-		outputter.SetError(err)
+		abort(s, outputter, rollback, "LoadBalancer config is failed", err)
 		return
 	} else {
-		emitCmd(s, result, true)
+		helper.EmitCmd(s, result, true)
 	}
 
 	fmt.Printf("\nyour stake id is %s \n", namespace)
 
+	if params.Resume == "" {
+		fmt.Printf("your chain id is %s \n", req.ChainID)
+	}
+
 	outputter.SetCommandResult(params.getResult())
 }
 
-func emitCmd(s *spinner.Spinner, value string, status bool) {
-	time.Sleep(2 * time.Second)
+// resolveChainId assigns a free chain id or makes sure the requested one is not used by another stack
+func resolveChainId(ctx context.Context, req chain.ConfigRequest) (chain.ConfigRequest, error) {
+	if params.AutoChainID {
+		id, err := chain.AssignChainId(ctx)
+		if err != nil {
+			return req, err
+		}
 
-	if status {
-		s.Stop()
-		fmt.Printf("\x1b[32m✓\x1b[0m %s\n", value)
-		s.Start()
-	} else {
-		s.Stop()
-		fmt.Printf("\x1b[31m✗\x1b[0m %s\n", value)
-		s.Start()
+		req.ChainID = id
+
+		return req, nil
+	}
+
+	req = req.WithDefaults()
+
+	return req, chain.CheckChainId(ctx, req.ChainID)
+}
+
+// runDryRun renders the stack manifests to stdout or into the output directory
+func runDryRun(outputter helper.OutputFormatter, req chain.ConfigRequest) {
+	stakeId := params.StakeId
+	if stakeId == "" {
+		stakeId = uuid.New().String()
+	}
+
+	objects, err := chain.RenderStack(stakeId, req)
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	if params.OutputDir != "" {
+		files, err := chain.WriteManifests(objects, params.OutputDir)
+		if err != nil {
+			outputter.SetError(err)
+			return
+		}
+
+		outputter.SetCommandResult(params.getRenderResult("", files))
+
+		return
+	}
+
+	manifests, err := chain.MarshalManifests(objects)
+	if err != nil {
+		outputter.SetError(err)
+		return
 	}
+
+	outputter.SetCommandResult(params.getRenderResult(manifests, nil))
+}
+
+// abort reports the failed step and, unless --keep-on-failure is set,
+// undoes every step that already ran
+func abort(s *spinner.Spinner, outputter helper.OutputFormatter, rollback *chain.Rollback, value string, err error) {
+	helper.EmitCmd(s, value, false)
+	defer s.Stop()
+
+	// a resumed stack existed before this run, so it is never rolled back
+	if params.KeepOnFailure || params.Resume != "" {
+		outputter.SetError(err)
+		return
+	}
+
+	messages, rollbackErr := rollback.Run()
+	for _, message := range messages {
+		helper.EmitCmd(s, message, true)
+	}
+
+	if rollbackErr != nil {
+		helper.EmitCmd(s, "Rollback is incomplete", false)
+		err = errors.Join(err, rollbackErr)
+	}
+
+	outputter.SetError(err)
 }
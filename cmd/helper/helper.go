@@ -5,21 +5,24 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"text/tabwriter"
+	"time"
 
+	"cli/cmd/config"
+
+	"github.com/briandowns/spinner"
 	"github.com/spf13/cobra"
 )
 
 const (
-	JSONOutputFlag  = "json"
-)
-
-type genesisParams struct {
-	VaultUrl   string
-	VaultToken string
-}
-
-var (
-	params = &genesisParams{}
+	JSONOutputFlag = "json"
+	DryRunFlag     = "dry-run"
+	VaultUrlFlag   = "vault-url"
+	VaultTokenFlag = "vault-token"
+	TimeoutFlag    = "timeout"
+
+	// OfflineAnnotation marks commands that never need a cluster connection
+	OfflineAnnotation = "offline"
 )
 
 type OutputFormatter interface {
@@ -74,6 +77,42 @@ func RegisterJSONOutputFlag(cmd *cobra.Command) {
 	)
 }
 
+// RegisterTimeoutFlag registers the --timeout bounding every step of the child commands
+func RegisterTimeoutFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().DurationVar(
+		&config.StepTimeout,
+		TimeoutFlag,
+		config.DefaultStepTimeout,
+		"how long a single step may wait for the cluster",
+	)
+}
+
+// RegisterVaultFlags registers the vault settings for all child commands, together with the
+// misspelled valut-* names kept for backwards compatibility
+func RegisterVaultFlags(cmd *cobra.Command) {
+	flags := cmd.PersistentFlags()
+
+	flags.StringVar(
+		&config.VaultUrl,
+		VaultUrlFlag,
+		"",
+		fmt.Sprintf("the vault address, defaults to $%s or the config file", config.VaultAddrEnv),
+	)
+
+	flags.StringVar(
+		&config.VaultToken,
+		VaultTokenFlag,
+		"",
+		fmt.Sprintf("the vault token, defaults to $%s or the config file", config.VaultTokenEnv),
+	)
+
+	flags.StringVar(&config.VaultUrl, "valut-url", "", "")
+	flags.StringVar(&config.VaultToken, "valut-token", "", "")
+
+	_ = flags.MarkDeprecated("valut-url", "use --"+VaultUrlFlag+" instead")
+	_ = flags.MarkDeprecated("valut-token", "use --"+VaultTokenFlag+" instead")
+}
+
 // WriteOutput implements OutputFormatter interface
 func (cli *cliOutput) WriteOutput() {
 	if cli.errorOutput != nil {
@@ -187,6 +226,35 @@ func InitializeOutputter(cmd *cobra.Command) OutputFormatter {
 	return newCLIOutput()
 }
 
+// IsOffline reports whether the command runs without a cluster connection
+func IsOffline(cmd *cobra.Command) bool {
+	if cmd.Annotations[OfflineAnnotation] == "true" {
+		return true
+	}
+
+	dryRunFlag := cmd.Flag(DryRunFlag)
+	if dryRunFlag == nil {
+		return false
+	}
+
+	return dryRunFlag.Value.String() == "true"
+}
+
+// EmitCmd prints the outcome of a single step without interrupting the running spinner
+func EmitCmd(s *spinner.Spinner, value string, status bool) {
+	time.Sleep(2 * time.Second)
+
+	if status {
+		s.Stop()
+		fmt.Printf("\x1b[32m✓\x1b[0m %s\n", value)
+		s.Start()
+	} else {
+		s.Stop()
+		fmt.Printf("\x1b[31m✗\x1b[0m %s\n", value)
+		s.Start()
+	}
+}
+
 func shouldOutputJSON(baseCmd *cobra.Command) bool {
 	jsonOutputFlag := baseCmd.Flag(JSONOutputFlag)
 	if jsonOutputFlag == nil {
@@ -210,3 +278,269 @@ func (r *GenesisResult) GetOutput() string {
 	return buffer.String()
 }
 
+
+type DestroyResult struct {
+	Message string `json:"message"`
+}
+
+func (r *DestroyResult) GetOutput() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("\n[DESTROY SUCCESS]\n")
+	buffer.WriteString(r.Message)
+
+	return buffer.String()
+}
+
+type UpgradeResult struct {
+	Message string `json:"message"`
+	Image   string `json:"image"`
+}
+
+func (r *UpgradeResult) GetOutput() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("\n[UPGRADE SUCCESS]\n")
+	buffer.WriteString(r.Message)
+
+	return buffer.String()
+}
+
+type StorageResult struct {
+	Message string `json:"message"`
+}
+
+func (r *StorageResult) GetOutput() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("\n[STORAGE SUCCESS]\n")
+	buffer.WriteString(r.Message)
+
+	return buffer.String()
+}
+
+type NodeStatusResult struct {
+	Name      string `json:"name"`
+	Ready     bool   `json:"ready"`
+	PodPhase  string `json:"podPhase"`
+	PVCPhase  string `json:"pvcPhase"`
+	ServiceIP string `json:"serviceIP"`
+}
+
+type StatusResult struct {
+	StakeId        string             `json:"stakeId"`
+	TotalNode      string             `json:"totalNode"`
+	JobStatus      string             `json:"jobStatus"`
+	PremineFund    string             `json:"premineFund"`
+	LoadBalancerIP string             `json:"loadBalancerIP"`
+	Health         string             `json:"health"`
+	Nodes          []NodeStatusResult `json:"nodes"`
+}
+
+func (r *StatusResult) GetOutput() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("\n[STACK STATUS]\n")
+
+	w := tabwriter.NewWriter(&buffer, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Stake ID\t%s\n", r.StakeId)
+	fmt.Fprintf(w, "Health\t%s\n", r.Health)
+	fmt.Fprintf(w, "Total Node\t%s\n", r.TotalNode)
+	fmt.Fprintf(w, "Helper Job\t%s\n", r.JobStatus)
+	fmt.Fprintf(w, "Premine Fund\t%s\n", r.PremineFund)
+	fmt.Fprintf(w, "LoadBalancer IP\t%s\n", r.LoadBalancerIP)
+	_ = w.Flush()
+
+	buffer.WriteString("\n[VALIDATORS]\n")
+
+	w = tabwriter.NewWriter(&buffer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tREADY\tPOD\tPVC\tSERVICE IP")
+
+	for _, node := range r.Nodes {
+		fmt.Fprintf(w, "%s\t%t\t%s\t%s\t%s\n", node.Name, node.Ready, node.PodPhase, node.PVCPhase, node.ServiceIP)
+	}
+
+	_ = w.Flush()
+
+	return buffer.String()
+}
+
+type StackListEntry struct {
+	StakeId        string `json:"stakeId"`
+	Name           string `json:"name"`
+	ChainId        string `json:"chainId"`
+	TotalNode      string `json:"totalNode"`
+	CreatedAt      string `json:"createdAt"`
+	Age            string `json:"age"`
+	LoadBalancerIP string `json:"loadBalancerIP"`
+	Health         string `json:"health"`
+}
+
+type ListResult struct {
+	Stacks []StackListEntry `json:"stacks"`
+}
+
+func (r *ListResult) GetOutput() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("\n[STACKS]\n")
+
+	if len(r.Stacks) == 0 {
+		buffer.WriteString("No stacks found")
+
+		return buffer.String()
+	}
+
+	w := tabwriter.NewWriter(&buffer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STAKE ID\tNAME\tCHAIN ID\tVALIDATORS\tAGE\tLOADBALANCER IP\tHEALTH")
+
+	for _, stack := range r.Stacks {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", stack.StakeId, stack.Name, stack.ChainId, stack.TotalNode, stack.Age, stack.LoadBalancerIP, stack.Health)
+	}
+
+	_ = w.Flush()
+
+	return buffer.String()
+}
+
+type RenderResult struct {
+	Manifests string   `json:"manifests,omitempty"`
+	OutputDir string   `json:"outputDir,omitempty"`
+	Files     []string `json:"files,omitempty"`
+}
+
+func (r *RenderResult) GetOutput() string {
+	if r.OutputDir == "" {
+		return r.Manifests
+	}
+
+	var buffer bytes.Buffer
+
+	buffer.WriteString("\n[RENDER SUCCESS]\n")
+	buffer.WriteString(fmt.Sprintf("%d manifests written to %s\n", len(r.Files), r.OutputDir))
+
+	for _, file := range r.Files {
+		buffer.WriteString(fmt.Sprintf("%s\n", file))
+	}
+
+	return buffer.String()
+}
+
+type ExportResult struct {
+	Format    string   `json:"format"`
+	OutputDir string   `json:"outputDir"`
+	Files     []string `json:"files"`
+}
+
+func (r *ExportResult) GetOutput() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("\n[EXPORT SUCCESS]\n")
+	buffer.WriteString(fmt.Sprintf("%s chart written to %s\n", r.Format, r.OutputDir))
+
+	for _, file := range r.Files {
+		buffer.WriteString(fmt.Sprintf("%s\n", file))
+	}
+
+	return buffer.String()
+}
+
+type SpecResult struct {
+	Message string `json:"message"`
+}
+
+func (r *SpecResult) GetOutput() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("\n[SPEC SUCCESS]\n")
+	buffer.WriteString(r.Message)
+
+	return buffer.String()
+}
+
+type ValidatorEntry struct {
+	Index   int    `json:"index"`
+	Address string `json:"address"`
+	NodeID  string `json:"nodeId,omitempty"`
+}
+
+type ValidatorResult struct {
+	Message    string           `json:"message"`
+	Validators []ValidatorEntry `json:"validators,omitempty"`
+}
+
+func (r *ValidatorResult) GetOutput() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("\n[VALIDATOR SUCCESS]\n")
+	buffer.WriteString(r.Message)
+
+	if len(r.Validators) == 0 {
+		return buffer.String()
+	}
+
+	w := tabwriter.NewWriter(&buffer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tADDRESS\tNODE ID")
+
+	for _, validator := range r.Validators {
+		fmt.Fprintf(w, "validator-node-%d\t%s\t%s\n", validator.Index, validator.Address, validator.NodeID)
+	}
+
+	_ = w.Flush()
+
+	return buffer.String()
+}
+
+type BackupEntry struct {
+	Backup      string `json:"backup"`
+	Index       int    `json:"index"`
+	Snapshot    string `json:"snapshot"`
+	BlockHeight string `json:"blockHeight"`
+	Ready       bool   `json:"ready"`
+	Size        string `json:"size,omitempty"`
+	Created     string `json:"created,omitempty"`
+}
+
+type BackupResult struct {
+	Message   string        `json:"message"`
+	Snapshots []BackupEntry `json:"snapshots,omitempty"`
+}
+
+func (r *BackupResult) GetOutput() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("\n[BACKUP SUCCESS]\n")
+	buffer.WriteString(r.Message)
+
+	if len(r.Snapshots) == 0 {
+		return buffer.String()
+	}
+
+	w := tabwriter.NewWriter(&buffer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "BACKUP\tNODE\tSNAPSHOT\tBLOCK\tREADY\tSIZE\tCREATED")
+
+	for _, snapshot := range r.Snapshots {
+		fmt.Fprintf(w, "%s\tvalidator-node-%d\t%s\t%s\t%t\t%s\t%s\n", snapshot.Backup, snapshot.Index, snapshot.Snapshot, snapshot.BlockHeight, snapshot.Ready, snapshot.Size, snapshot.Created)
+	}
+
+	_ = w.Flush()
+
+	return buffer.String()
+}
+
+type ArchiveResult struct {
+	Message string `json:"message"`
+	File    string `json:"file"`
+	First   uint64 `json:"first"`
+	Last    uint64 `json:"last"`
+	Blocks  int    `json:"blocks"`
+}
+
+func (r *ArchiveResult) GetOutput() string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString("\n[ARCHIVE SUCCESS]\n")
+	buffer.WriteString(r.Message)
+
+	return buffer.String()
+}
@@ -0,0 +1,52 @@
+package list
+
+import (
+	"time"
+
+	"cli/cmd/chain"
+	"cli/cmd/helper"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/duration"
+)
+
+func GetCommand() *cobra.Command {
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "Lists every stack created by the cli",
+		Args:  cobra.NoArgs,
+		Run:   runCommand,
+	}
+
+	return listCmd
+}
+
+func runCommand(cmd *cobra.Command, _ []string) {
+	outputter := helper.InitializeOutputter(cmd)
+	defer outputter.WriteOutput()
+
+	stacks, err := chain.ListStacks(cmd.Context())
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	result := &helper.ListResult{
+		Stacks: make([]helper.StackListEntry, 0, len(stacks)),
+	}
+
+	for _, stack := range stacks {
+		result.Stacks = append(result.Stacks, helper.StackListEntry{
+			StakeId:        stack.StakeId,
+			Name:           stack.Name,
+			ChainId:        stack.ChainId,
+			TotalNode:      stack.TotalNode,
+			CreatedAt:      stack.CreatedAt.UTC().Format(time.RFC3339),
+			Age:            duration.HumanDuration(time.Since(stack.CreatedAt)),
+			LoadBalancerIP: stack.LoadBalancerIP,
+			Health:         stack.Health,
+		})
+	}
+
+	outputter.SetCommandResult(result)
+}
@@ -1,10 +1,24 @@
 package root
 
 import (
+	"cli/cmd/archive"
+	"cli/cmd/backup"
+	"cli/cmd/config"
+	"cli/cmd/destroy"
+	"cli/cmd/export"
 	"cli/cmd/genesis"
 	"cli/cmd/helper"
+	"cli/cmd/list"
+	"cli/cmd/spec"
+	"cli/cmd/status"
+	"cli/cmd/storage"
+	"cli/cmd/upgrade"
+	"cli/cmd/validator"
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 )
@@ -17,10 +31,24 @@ func NewRootCommand() *RootCommand  {
 	rootCommand := &RootCommand{
 		baseCmd: &cobra.Command{
 			Short: "CCL-Polygon-Edge-BaaS",
+			PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+				if err := config.LoadVaultConfig(); err != nil {
+					return err
+				}
+
+				// offline commands render manifests and must work without a cluster
+				if !helper.IsOffline(cmd) {
+					config.InitConfig()
+				}
+
+				return nil
+			},
 		},
 	}
 
 	helper.RegisterJSONOutputFlag(rootCommand.baseCmd)
+	helper.RegisterVaultFlags(rootCommand.baseCmd)
+	helper.RegisterTimeoutFlag(rootCommand.baseCmd)
 
 	rootCommand.registerSubCommands()
 
@@ -30,11 +58,31 @@ func NewRootCommand() *RootCommand  {
 func (rc *RootCommand) registerSubCommands() {
 	rc.baseCmd.AddCommand(
 		genesis.GetCommand(),
+		genesis.GetRenderCommand(),
+		destroy.GetCommand(),
+		status.GetCommand(),
+		list.GetCommand(),
+		export.GetCommand(),
+		spec.GetCommand(),
+		validator.GetCommand(),
+		upgrade.GetCommand(),
+		storage.GetCommand(),
+		backup.GetCommand(),
+		archive.GetCommand(),
 	)
 }
 
 func (rc *RootCommand) Execute() {
-	if err := rc.baseCmd.Execute(); err != nil {
+	// the first interrupt cancels the running step, a second one kills the cli right away
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	if err := rc.baseCmd.ExecuteContext(ctx); err != nil {
 		_, _ = fmt.Fprintln(os.Stderr, err)
 
 		os.Exit(1)
@@ -0,0 +1,78 @@
+package initialize
+
+import (
+	"fmt"
+	"os"
+
+	"cli/cmd/chain"
+	"cli/cmd/helper"
+
+	"github.com/spf13/cobra"
+)
+
+type initParams struct {
+	File  string
+	Force bool
+}
+
+var (
+	params = &initParams{}
+)
+
+const (
+	File  = "file"
+	Force = "force"
+)
+
+func GetCommand() *cobra.Command {
+	initCmd := &cobra.Command{
+		Use:         "init",
+		Short:       "Writes an annotated stack spec template",
+		Annotations: map[string]string{helper.OfflineAnnotation: "true"},
+		PreRunE:     preRunCommand,
+		Run:         runCommand,
+	}
+
+	setFlags(initCmd)
+
+	return initCmd
+}
+
+func setFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVarP(
+		&params.File,
+		File,
+		"f",
+		"stack.yaml",
+		"the path to write the template to",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.Force,
+		Force,
+		false,
+		"overwrite the file if it already exists",
+	)
+}
+
+func preRunCommand(cmd *cobra.Command, _ []string) error {
+	if _, err := os.Stat(params.File); err == nil && !params.Force {
+		return fmt.Errorf("%s already exists, use --%s to overwrite it", params.File, Force)
+	}
+
+	return nil
+}
+
+func runCommand(cmd *cobra.Command, _ []string) {
+	outputter := helper.InitializeOutputter(cmd)
+	defer outputter.WriteOutput()
+
+	if err := os.WriteFile(params.File, []byte(chain.StackSpecTemplate), 0644); err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	outputter.SetCommandResult(&helper.SpecResult{
+		Message: fmt.Sprintf("\nStack spec template written to %s \n", params.File),
+	})
+}
@@ -0,0 +1,20 @@
+package spec
+
+import (
+	"cli/cmd/spec/initialize"
+
+	"github.com/spf13/cobra"
+)
+
+func GetCommand() *cobra.Command {
+	specCmd := &cobra.Command{
+		Use:   "spec",
+		Short: "Top level command for working with stack spec files used by genesis --file",
+	}
+
+	specCmd.AddCommand(
+		initialize.GetCommand(),
+	)
+
+	return specCmd
+}
@@ -0,0 +1,61 @@
+package status
+
+import (
+	"cli/cmd/chain"
+	"cli/cmd/helper"
+
+	"github.com/spf13/cobra"
+)
+
+func GetCommand() *cobra.Command {
+	statusCmd := &cobra.Command{
+		Use:     "status <stake-id>",
+		Short:   "Reports the health of a stack created by genesis",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: preRunCommand,
+		Run:     runCommand,
+	}
+
+	return statusCmd
+}
+
+func preRunCommand(cmd *cobra.Command, args []string) error {
+	return chain.ValidateStack(cmd.Context(), args[0])
+}
+
+func runCommand(cmd *cobra.Command, args []string) {
+	outputter := helper.InitializeOutputter(cmd)
+	defer outputter.WriteOutput()
+
+	status, err := chain.GetStackStatus(cmd.Context(), args[0])
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	outputter.SetCommandResult(newStatusResult(status))
+}
+
+func newStatusResult(status *chain.StackStatus) *helper.StatusResult {
+	result := &helper.StatusResult{
+		StakeId:        status.StakeId,
+		TotalNode:      status.TotalNode,
+		JobStatus:      status.JobStatus,
+		PremineFund:    status.PremineFund,
+		LoadBalancerIP: status.LoadBalancerIP,
+		Health:         status.Health,
+		Nodes:          make([]helper.NodeStatusResult, 0, len(status.Nodes)),
+	}
+
+	for _, node := range status.Nodes {
+		result.Nodes = append(result.Nodes, helper.NodeStatusResult{
+			Name:      node.Name,
+			Ready:     node.Ready,
+			PodPhase:  node.PodPhase,
+			PVCPhase:  node.PVCPhase,
+			ServiceIP: node.ServiceIP,
+		})
+	}
+
+	return result
+}
@@ -0,0 +1,134 @@
+package resize
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"cli/cmd/chain"
+	"cli/cmd/helper"
+
+	"github.com/briandowns/spinner"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+type resizeParams struct {
+	Size string
+	Node int
+
+	size resource.Quantity
+}
+
+var (
+	params = &resizeParams{}
+)
+
+const (
+	Size = "size"
+	Node = "node"
+)
+
+func GetCommand() *cobra.Command {
+	resizeCmd := &cobra.Command{
+		Use:     "resize <stake-id>",
+		Short:   "Grows the validator data volumes of a stack",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: preRunCommand,
+		Run:     runCommand,
+	}
+
+	setFlags(resizeCmd)
+
+	return resizeCmd
+}
+
+func setFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&params.Size,
+		Size,
+		"",
+		"the new size of the volumes, for example 50Gi",
+	)
+
+	cmd.Flags().IntVar(
+		&params.Node,
+		Node,
+		0,
+		"resize the volume of this validator only (default every validator)",
+	)
+}
+
+func preRunCommand(cmd *cobra.Command, args []string) error {
+	if params.Size == "" {
+		return errors.New("size is required")
+	}
+
+	size, err := resource.ParseQuantity(params.Size)
+	if err != nil {
+		return fmt.Errorf("invalid size %q: %w", params.Size, err)
+	}
+
+	params.size = size
+
+	return chain.ValidateStack(cmd.Context(), args[0])
+}
+
+func runCommand(cmd *cobra.Command, args []string) {
+	outputter := helper.InitializeOutputter(cmd)
+	defer outputter.WriteOutput()
+
+	namespace := args[0]
+
+	indexes, err := chain.ValidatorIndexes(cmd.Context(), namespace)
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	if params.Node != 0 {
+		found := false
+		for _, i := range indexes {
+			found = found || i == params.Node
+		}
+
+		if !found {
+			outputter.SetError(fmt.Errorf("validator %d is not part of stack %s", params.Node, namespace))
+			return
+		}
+
+		indexes = []int{params.Node}
+	}
+
+	s := spinner.New(spinner.CharSets[14], 110*time.Millisecond, spinner.WithColor("cyan"))
+
+	fmt.Println("\n ")
+	s.Suffix = " Running..."
+	s.Start()
+	defer s.Stop()
+
+	// one volume at a time, a restarted pod only takes its own validator offline
+	for _, i := range indexes {
+		result, err := chain.ResizeValidatorVolume(cmd.Context(), namespace, i, params.size)
+		if err != nil {
+			helper.EmitCmd(s, fmt.Sprintf("Validator %d volume resize is failed", i), false)
+			outputter.SetError(err)
+			return
+		} else {
+			helper.EmitCmd(s, result, true)
+		}
+	}
+
+	// validators added later get the size every validator has
+	if params.Node == 0 {
+		if err := chain.SetStorageSize(cmd.Context(), namespace, params.size); err != nil {
+			helper.EmitCmd(s, "Stack storage size update is failed", false)
+			outputter.SetError(err)
+			return
+		}
+	}
+
+	outputter.SetCommandResult(&helper.StorageResult{
+		Message: fmt.Sprintf("\n%d volumes of stack %s resized to %s \n", len(indexes), namespace, params.size.String()),
+	})
+}
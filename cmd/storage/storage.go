@@ -0,0 +1,20 @@
+package storage
+
+import (
+	"cli/cmd/storage/resize"
+
+	"github.com/spf13/cobra"
+)
+
+func GetCommand() *cobra.Command {
+	storageCmd := &cobra.Command{
+		Use:   "storage",
+		Short: "Top level command for managing the validator data volumes of a stack",
+	}
+
+	storageCmd.AddCommand(
+		resize.GetCommand(),
+	)
+
+	return storageCmd
+}
@@ -0,0 +1,104 @@
+package upgrade
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"cli/cmd/chain"
+	"cli/cmd/helper"
+
+	"github.com/briandowns/spinner"
+	"github.com/spf13/cobra"
+)
+
+type upgradeParams struct {
+	Image string
+}
+
+var (
+	params = &upgradeParams{}
+)
+
+const (
+	Image = "image"
+)
+
+func GetCommand() *cobra.Command {
+	upgradeCmd := &cobra.Command{
+		Use:     "upgrade <stake-id>",
+		Short:   "Rolls a new polygon-edge image out to the validators of a stack, one at a time",
+		Args:    cobra.ExactArgs(1),
+		PreRunE: preRunCommand,
+		Run:     runCommand,
+	}
+
+	setFlags(upgradeCmd)
+
+	return upgradeCmd
+}
+
+func setFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&params.Image,
+		Image,
+		"",
+		"the polygon-edge image, or a tag of the image the stack runs",
+	)
+}
+
+func preRunCommand(cmd *cobra.Command, args []string) error {
+	if params.Image == "" {
+		return errors.New("image is required")
+	}
+
+	return chain.ValidateStack(cmd.Context(), args[0])
+}
+
+func runCommand(cmd *cobra.Command, args []string) {
+	outputter := helper.InitializeOutputter(cmd)
+	defer outputter.WriteOutput()
+
+	namespace := args[0]
+	image, err := chain.UpgradeImage(cmd.Context(), namespace, params.Image)
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	indexes, err := chain.ValidatorIndexes(cmd.Context(), namespace)
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	s := spinner.New(spinner.CharSets[14], 110*time.Millisecond, spinner.WithColor("cyan"))
+
+	fmt.Println("\n ")
+	s.Suffix = " Running..."
+	s.Start()
+	defer s.Stop()
+
+	// the next validator is only touched once the chain made progress with the previous one
+	for n, i := range indexes {
+		result, err := chain.UpgradeValidator(cmd.Context(), namespace, i, image)
+		if err != nil {
+			helper.EmitCmd(s, fmt.Sprintf("Validator %d upgrade is failed, %d of %d validators run %s", i, n, len(indexes), image), false)
+			outputter.SetError(err)
+			return
+		} else {
+			helper.EmitCmd(s, result, true)
+		}
+	}
+
+	if err := chain.SetStackImage(cmd.Context(), namespace, image); err != nil {
+		helper.EmitCmd(s, "Stack image update is failed", false)
+		outputter.SetError(err)
+		return
+	}
+
+	outputter.SetCommandResult(&helper.UpgradeResult{
+		Message: fmt.Sprintf("\nStack %s upgraded to %s \n", namespace, image),
+		Image:   image,
+	})
+}
@@ -0,0 +1,244 @@
+package add
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"cli/cmd/chain"
+	"cli/cmd/helper"
+
+	"github.com/briandowns/spinner"
+	"github.com/spf13/cobra"
+)
+
+type addParams struct {
+	Count int
+}
+
+var (
+	params = &addParams{}
+)
+
+const (
+	Count = "count"
+)
+
+func GetCommand() *cobra.Command {
+	addCmd := &cobra.Command{
+		Use:   "add <stake-id>",
+		Short: "Adds validators to a running stack and votes them into the validator set",
+		Long: `Adds validators to a running stack and votes them into the validator set.
+
+A run which fails before the votes removes the validators it created. Once the new validators
+run they are recorded on the stack, a run whose votes fail leaves them running and the next run
+resumes their votes instead of adding new validators.`,
+		Args:    cobra.ExactArgs(1),
+		PreRunE: preRunCommand,
+		Run:     runCommand,
+	}
+
+	setFlags(addCmd)
+
+	return addCmd
+}
+
+func setFlags(cmd *cobra.Command) {
+	cmd.Flags().IntVar(
+		&params.Count,
+		Count,
+		1,
+		"the number of validators to add",
+	)
+}
+
+func preRunCommand(cmd *cobra.Command, args []string) error {
+	if params.Count < 1 {
+		return errors.New("count must be at least 1")
+	}
+
+	if err := chain.ValidateStack(cmd.Context(), args[0]); err != nil {
+		return err
+	}
+
+	return chain.CheckValidatorChange(cmd.Context(), args[0])
+}
+
+func runCommand(cmd *cobra.Command, args []string) {
+	outputter := helper.InitializeOutputter(cmd)
+	defer outputter.WriteOutput()
+
+	namespace := args[0]
+
+	voters, err := chain.ValidatorIndexes(cmd.Context(), namespace)
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	pending, err := chain.PendingValidators(cmd.Context(), namespace)
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	// the validators already in the set vote for the new ones, they have to keep a majority of
+	// the set as it grows or the last candidates can not be voted in
+	if len(pending) == 0 && params.Count > len(voters) {
+		outputter.SetError(fmt.Errorf("a stack of %d validators can add at most %d at a time", len(voters), len(voters)))
+		return
+	}
+
+	s := spinner.New(spinner.CharSets[14], 110*time.Millisecond, spinner.WithColor("cyan"))
+
+	fmt.Println("\n ")
+	s.Suffix = " Running..."
+	s.Start()
+	defer s.Stop()
+
+	var validators []chain.ValidatorKeys
+	if len(pending) > 0 {
+		// an earlier run left validators running whose votes were not sealed, they are voted in
+		// instead of new ones, their pods already run with the keys they were voted for with
+		validators = pending
+		voters = withoutValidators(voters, pending)
+		helper.EmitCmd(s, fmt.Sprintf("Resuming the votes of %d validators of an earlier run 🗳️", len(pending)), true)
+	} else {
+		validators, err = addValidators(cmd, s, namespace, voters)
+		if err != nil {
+			outputter.SetError(err)
+			return
+		}
+	}
+
+	result, err := chain.ProposeValidators(cmd.Context(), namespace, voters, validators, chain.VoteAuth)
+	if err != nil {
+		helper.EmitCmd(s, "Validator votes are failed", false)
+		outputter.SetError(fmt.Errorf("%w, run validator add %s again to resume the votes", err, namespace))
+		return
+	} else {
+		helper.EmitCmd(s, result, true)
+	}
+
+	if err := chain.SetPendingValidators(cmd.Context(), namespace, nil); err != nil {
+		helper.EmitCmd(s, "Validator count update is failed", false)
+		outputter.SetError(err)
+		return
+	}
+
+	entries := []helper.ValidatorEntry{}
+	for _, validator := range validators {
+		entries = append(entries, helper.ValidatorEntry{
+			Index:   validator.Index,
+			Address: validator.Address,
+			NodeID:  validator.NodeID,
+		})
+	}
+
+	outputter.SetCommandResult(&helper.ValidatorResult{
+		Message:    fmt.Sprintf("\n%d validators added to stack %s, they joined the validator set \n", len(validators), namespace),
+		Validators: entries,
+	})
+}
+
+// addValidators creates the secrets and the nodes of new validators and records them as pending
+// members of the stack. A failed step rolls back what this run created
+func addValidators(cmd *cobra.Command, s *spinner.Spinner, namespace string, voters []int) ([]chain.ValidatorKeys, error) {
+	// validators voted out by a failed remove still have their nodes
+	removals, err := chain.PendingRemovals(cmd.Context(), namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// new validators take the indexes after the highest one, indexes of removed validators are not reused
+	first := 1
+	for _, index := range append(append([]int{}, voters...), removals...) {
+		if index >= first {
+			first = index + 1
+		}
+	}
+
+	last := first + params.Count - 1
+
+	rollback := chain.NewRollback()
+
+	rollback.Add("Validator-Secrets", func(ctx context.Context) (string, error) {
+		return chain.RemoveValidatorSecrets(ctx, namespace, first, last)
+	})
+
+	validators, err := chain.CreateValidatorSecrets(cmd.Context(), namespace, first, last)
+	if err != nil {
+		return nil, abort(s, rollback, "Validator secrets are failed", err)
+	} else {
+		helper.EmitCmd(s, "Validator secrets are successfully generated 🔑", true)
+	}
+
+	rollback.Add("Validator-Nodes", func(ctx context.Context) (string, error) {
+		return chain.RemoveValidatorNodes(ctx, namespace, first, last)
+	})
+
+	result, err := chain.CreateValidatorNodes(cmd.Context(), namespace, first, last)
+	if err != nil {
+		return nil, abort(s, rollback, "Validator nodes are failed", err)
+	} else {
+		helper.EmitCmd(s, result, true)
+	}
+
+	rollback.Add("Validator-Pending", func(ctx context.Context) (string, error) {
+		return "Pending validators are successfully cleared 🗳️", chain.SetPendingValidators(ctx, namespace, nil)
+	})
+
+	// the new validators are recorded before the votes, votes which were sealed can not be rolled
+	// back, so a failed vote is resumed by the next run
+	indexes := voters
+	for i := first; i <= last; i++ {
+		indexes = append(indexes, i)
+	}
+
+	if err := chain.SetPendingValidators(cmd.Context(), namespace, validators); err != nil {
+		return nil, abort(s, rollback, "Validator count update is failed", err)
+	}
+
+	if err := chain.SetValidatorIndexes(cmd.Context(), namespace, indexes); err != nil {
+		return nil, abort(s, rollback, "Validator count update is failed", err)
+	}
+
+	return validators, nil
+}
+
+// abort reports the failed step and undoes every step of the run that already ran
+func abort(s *spinner.Spinner, rollback *chain.Rollback, value string, err error) error {
+	helper.EmitCmd(s, value, false)
+
+	messages, rollbackErr := rollback.Run()
+	for _, message := range messages {
+		helper.EmitCmd(s, message, true)
+	}
+
+	if rollbackErr != nil {
+		helper.EmitCmd(s, "Rollback is incomplete", false)
+		err = errors.Join(err, rollbackErr)
+	}
+
+	return err
+}
+
+// withoutValidators returns the indexes which do not belong to any of the validators
+func withoutValidators(indexes []int, validators []chain.ValidatorKeys) []int {
+	var remaining []int
+	for _, index := range indexes {
+		found := false
+		for _, validator := range validators {
+			if validator.Index == index {
+				found = true
+			}
+		}
+
+		if !found {
+			remaining = append(remaining, index)
+		}
+	}
+
+	return remaining
+}
@@ -0,0 +1,184 @@
+package remove
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"cli/cmd/chain"
+	"cli/cmd/helper"
+
+	"github.com/briandowns/spinner"
+	"github.com/spf13/cobra"
+)
+
+type removeParams struct {
+	KeepPVC      bool
+	PurgeSecrets bool
+	Force        bool
+}
+
+var (
+	params = &removeParams{}
+)
+
+const (
+	KeepPVC      = "keep-pvc"
+	PurgeSecrets = "purge-secrets"
+	Force        = "force"
+)
+
+func GetCommand() *cobra.Command {
+	removeCmd := &cobra.Command{
+		Use:   "remove <stake-id> <node-index>",
+		Short: "Votes a validator out of the validator set and removes its node",
+		Long: `Votes a validator out of the validator set and removes its node.
+
+A removal which leaves the set tolerating fewer faulty validators needs --force. Once the vote
+is sealed the validator is recorded as removed before its node goes away, a run which fails
+after the vote is finished by running the same remove again.`,
+		Args:    cobra.ExactArgs(2),
+		PreRunE: preRunCommand,
+		Run:     runCommand,
+	}
+
+	setFlags(removeCmd)
+
+	return removeCmd
+}
+
+func setFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVar(
+		&params.KeepPVC,
+		KeepPVC,
+		false,
+		"keep the data volume of the validator",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.PurgeSecrets,
+		PurgeSecrets,
+		false,
+		"remove the validator secrets from the secrets backend",
+	)
+
+	cmd.Flags().BoolVar(
+		&params.Force,
+		Force,
+		false,
+		"remove the validator even when the remaining set tolerates fewer faulty validators",
+	)
+}
+
+func preRunCommand(cmd *cobra.Command, args []string) error {
+	index, err := strconv.Atoi(args[1])
+	if err != nil {
+		return fmt.Errorf("invalid node index %q", args[1])
+	}
+
+	if err := chain.ValidateStack(cmd.Context(), args[0]); err != nil {
+		return err
+	}
+
+	if err := chain.CheckValidatorChange(cmd.Context(), args[0]); err != nil {
+		return err
+	}
+
+	return chain.CheckValidatorRemoval(cmd.Context(), args[0], index, params.PurgeSecrets, params.Force)
+}
+
+func runCommand(cmd *cobra.Command, args []string) {
+	outputter := helper.InitializeOutputter(cmd)
+	defer outputter.WriteOutput()
+
+	namespace := args[0]
+	index, _ := strconv.Atoi(args[1])
+
+	// the validator votes for its own removal, the drop needs a majority of the current set
+	voters, err := chain.ValidatorIndexes(cmd.Context(), namespace)
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	pending, err := chain.PendingRemovals(cmd.Context(), namespace)
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	s := spinner.New(spinner.CharSets[14], 110*time.Millisecond, spinner.WithColor("cyan"))
+
+	fmt.Println("\n ")
+	s.Suffix = " Running..."
+	s.Start()
+	defer s.Stop()
+
+	if containsIndex(pending, index) {
+		// an earlier run voted the validator out but did not remove its node
+		helper.EmitCmd(s, fmt.Sprintf("Resuming the removal of validator %d of an earlier run 🗳️", index), true)
+	} else {
+		result, err := chain.ProposeValidators(cmd.Context(), namespace, voters, []chain.ValidatorKeys{{Index: index}}, chain.VoteDrop)
+		if err != nil {
+			helper.EmitCmd(s, "Validator votes are failed", false)
+			outputter.SetError(err)
+			return
+		} else {
+			helper.EmitCmd(s, result, true)
+		}
+	}
+
+	// the sealed vote is recorded before the node goes away, so the next add or remove does not
+	// count on a voter which no longer exists
+	if err := chain.StartValidatorRemoval(cmd.Context(), namespace, index); err != nil {
+		helper.EmitCmd(s, "Validator count update is failed", false)
+		outputter.SetError(fmt.Errorf("%w, run validator remove %s %d again to finish the removal", err, namespace, index))
+		return
+	}
+
+	result, err := chain.RemoveValidatorNode(cmd.Context(), namespace, index, params.KeepPVC)
+	if err != nil {
+		helper.EmitCmd(s, "Validator node removal is failed", false)
+		outputter.SetError(fmt.Errorf("%w, run validator remove %s %d again to finish the removal", err, namespace, index))
+		return
+	} else {
+		helper.EmitCmd(s, result, true)
+	}
+
+	if err := chain.FinishValidatorRemoval(cmd.Context(), namespace, index); err != nil {
+		helper.EmitCmd(s, "Validator count update is failed", false)
+		outputter.SetError(err)
+		return
+	}
+
+	indexes, err := chain.ValidatorIndexes(cmd.Context(), namespace)
+	if err != nil {
+		outputter.SetError(err)
+		return
+	}
+
+	if params.PurgeSecrets {
+		result, err = chain.PurgeValidatorSecrets(cmd.Context(), namespace, index)
+		if err != nil {
+			helper.EmitCmd(s, "Validator secrets purge is failed", false)
+			outputter.SetError(err)
+			return
+		} else {
+			helper.EmitCmd(s, result, true)
+		}
+	}
+
+	outputter.SetCommandResult(&helper.ValidatorResult{
+		Message: fmt.Sprintf("\nValidator %d removed from stack %s, %d validators remain \n", index, namespace, len(indexes)),
+	})
+}
+
+func containsIndex(indexes []int, index int) bool {
+	for _, i := range indexes {
+		if i == index {
+			return true
+		}
+	}
+
+	return false
+}
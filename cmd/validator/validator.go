@@ -0,0 +1,22 @@
+package validator
+
+import (
+	"cli/cmd/validator/add"
+	"cli/cmd/validator/remove"
+
+	"github.com/spf13/cobra"
+)
+
+func GetCommand() *cobra.Command {
+	validatorCmd := &cobra.Command{
+		Use:   "validator",
+		Short: "Top level command for changing the validator set of a stack",
+	}
+
+	validatorCmd.AddCommand(
+		add.GetCommand(),
+		remove.GetCommand(),
+	)
+
+	return validatorCmd
+}
@@ -4,12 +4,9 @@ import (
 	"fmt"
 
 	"cli/cmd/root"
-	"cli/cmd/config"
 )
 
 func main() {
-    config.InitConfig()
- 
     root.NewRootCommand().Execute()
  
     fmt.Println("")